@@ -1,5 +1,4 @@
-go build -o opaproxy \
-  opaproxy.go
+go build -o opaproxy .
 
 if [[ $? == 0 ]]; then
     ./opaproxy -debug=3 \
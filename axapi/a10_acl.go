@@ -0,0 +1,524 @@
+//
+//  a10_acl.go  --  Extended Access-List related aXAPI API calls
+//
+//  John D. Allen
+//  Sr. Solutions Engineer
+//  A10 Networks, Inc.
+//
+//  Copyright A10 Networks (c) 2020, All Rights Reserved.
+//
+
+package axapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// ACL holds an IPv4 extended 'access-list' (IDs 100-199). Rules are applied in
+// Seq order.
+type ACL struct {
+	ID    int
+	Rules []ACLRule
+}
+
+// ACLRule is one rule of an extended access-list. Src & Dst are "any", a host
+// address, or a CIDR prefix (IE> "10.1.0.0/16"). SrcPort & DstPort are only used
+// with "tcp" & "udp", and are either a single port ("443") or a range ("8000-8080");
+// "" matches any port.
+type ACLRule struct {
+	Seq      int
+	Action   string // "permit" or "deny"
+	Protocol string // "ip", "tcp", "udp" or "icmp"
+	Src      string
+	SrcPort  string
+	Dst      string
+	DstPort  string
+	Remark   string
+}
+
+// Normalize returns the rule the way the Thunder will hand it back, so that two
+// rules meaning the same thing compare equal with ==. IE> a prefix with host
+// bits set ("10.1.2.3/16") becomes "10.1.0.0/16", and a /32 becomes a host.
+//-----------------------------------------------------------------------------
+func (r ACLRule) Normalize() ACLRule {
+	r.Action = strings.ToLower(r.Action)
+	r.Protocol = strings.ToLower(r.Protocol)
+	if r.Protocol == "" {
+		r.Protocol = "ip"
+	}
+	norm := func(a string) string {
+		if a == "" || a == "any" || a == "0.0.0.0/0" {
+			return "any"
+		}
+		if _, n, err := net.ParseCIDR(a); err == nil {
+			if ones, _ := n.Mask.Size(); ones == 32 {
+				return n.IP.String()
+			}
+			return n.String()
+		}
+		return a
+	}
+	r.Src = norm(r.Src)
+	r.Dst = norm(r.Dst)
+	if r.Protocol != "tcp" && r.Protocol != "udp" {
+		r.SrcPort, r.DstPort = "", ""
+	}
+	return r
+}
+
+// aclMaxSeq is the highest rule seq-num an access-list can have
+const aclMaxSeq = 8192
+
+// DiffACLRules works out how to turn the rules of an access-list on the Thunder
+// (cur) into the wanted rules (want, in order -- their Seq is ignored) without a
+// wanted rule going missing part way through. Rules are matched by what they do,
+// not by Seq, so inserting, moving or removing a rule leaves the others alone. New
+// rules get a Seq in the gap between the kept rules on either side; only when a
+// gap is too small is the whole list re-added after the current last rule.
+// Make the changes in order: add, then upd (remark changes, in place), then del.
+//-----------------------------------------------------------------------------
+func DiffACLRules(cur []ACLRule, want []ACLRule) ([]ACLRule, []ACLRule, []ACLRule, error) {
+	cur = append([]ACLRule(nil), cur...)
+	sort.Slice(cur, func(i, j int) bool { return cur[i].Seq < cur[j].Seq })
+	key := func(r ACLRule) ACLRule {
+		r.Seq, r.Remark = 0, ""
+		return r
+	}
+
+	//
+	// Keep the longest run of current rules that are still wanted in the same order
+	n, m := len(cur), len(want)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case key(cur[i]) == key(want[j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	kept := make([]int, m) // index in cur of the rule kept for want[j], or -1
+	keptCur := make([]bool, n)
+	for j := range kept {
+		kept[j] = -1
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case key(cur[i]) == key(want[j]):
+			kept[j], keptCur[i] = i, true
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	//
+	// Find a free Seq for each new rule, between the kept rules on either side
+	used := map[int]bool{}
+	for _, r := range cur {
+		used[r.Seq] = true
+	}
+	seqs := make([]int, m)
+	renum := false
+	for j := 0; j < m && !renum; {
+		if kept[j] >= 0 {
+			seqs[j] = cur[kept[j]].Seq
+			j++
+			continue
+		}
+		k := j
+		for k < m && kept[k] < 0 {
+			k++
+		}
+		lo, hi := 0, aclMaxSeq+1
+		if j > 0 {
+			lo = seqs[j-1]
+		}
+		if k < m {
+			hi = cur[kept[k]].Seq
+		}
+		var free []int
+		for s := lo + 1; s < hi; s++ {
+			if !used[s] {
+				free = append(free, s)
+			}
+		}
+		if len(free) < k-j {
+			renum = true
+			break
+		}
+		for x := j; x < k; x++ {
+			if k == m {
+				// -- At the end, carry on in steps of 10
+				s := lo + 10*(x-j+1)
+				if x > j && s <= seqs[x-1] {
+					s = seqs[x-1] + 1
+				}
+				for used[s] {
+					s++
+				}
+				if s >= hi {
+					renum = true
+					break
+				}
+				seqs[x] = s
+				used[s] = true
+				continue
+			}
+			// -- In between, spread them out over the gap
+			seqs[x] = free[(x-j+1)*len(free)/(k-j+1)]
+		}
+		j = k
+	}
+	if renum {
+		base := 0
+		if n > 0 {
+			base = cur[n-1].Seq / 10 * 10
+		}
+		if base+10*m > aclMaxSeq {
+			return nil, nil, nil, errors.New("No room to renumber the access-list rules")
+		}
+		for j := range seqs {
+			seqs[j] = base + 10*(j+1)
+			kept[j] = -1
+		}
+		for i := range keptCur {
+			keptCur[i] = false
+		}
+	}
+
+	var add, upd, del []ACLRule
+	for j, r := range want {
+		r.Seq = seqs[j]
+		switch {
+		case kept[j] < 0:
+			add = append(add, r)
+		case cur[kept[j]] != r:
+			upd = append(upd, r)
+		}
+	}
+	for i, r := range cur {
+		if !keptCur[i] {
+			del = append(del, r)
+		}
+	}
+	for _, l := range [][]ACLRule{add, upd, del} {
+		sort.Slice(l, func(i, j int) bool { return l[i].Seq < l[j].Seq })
+	}
+	return add, upd, del, nil
+}
+
+// aclAddr sets the '<pfx>-any', '<pfx>-host' or '<pfx>-subnet' & '<pfx>-mask'
+// KVs for an address. The mask is sent as a wildcard (IE> 0.0.255.255).
+//-----------------------------------------------------------------------------
+func aclAddr(m map[string]interface{}, pfx string, a string) error {
+	if a == "any" {
+		m[pfx+"-any"] = 1
+		return nil
+	}
+	if !strings.Contains(a, "/") {
+		if ip := net.ParseIP(a); ip == nil || ip.To4() == nil {
+			return errors.New("Invalid IPv4 address '" + a + "' in access-list rule")
+		}
+		m[pfx+"-host"] = a
+		return nil
+	}
+	_, n, err := net.ParseCIDR(a)
+	if err != nil || n.IP.To4() == nil {
+		return errors.New("Invalid IPv4 prefix '" + a + "' in access-list rule")
+	}
+	wc := make(net.IP, 4)
+	for i, b := range n.Mask[len(n.Mask)-4:] {
+		wc[i] = ^b
+	}
+	m[pfx+"-subnet"] = n.IP.String()
+	m[pfx+"-mask"] = wc.String()
+	return nil
+}
+
+// aclPort sets the '<pfx>-eq', or '<pfx>-range' & '<pfx>-port-end' KVs for a port
+//-----------------------------------------------------------------------------
+func aclPort(m map[string]interface{}, pfx string, p string) error {
+	if p == "" {
+		return nil
+	}
+	lo, hi := p, ""
+	if i := strings.Index(p, "-"); i > 0 {
+		lo, hi = p[:i], p[i+1:]
+	}
+	l, err := strconv.Atoi(lo)
+	if err != nil {
+		return errors.New("Invalid port '" + p + "' in access-list rule")
+	}
+	if hi == "" {
+		m[pfx+"-eq"] = l
+		return nil
+	}
+	h, err := strconv.Atoi(hi)
+	if err != nil || h < l {
+		return errors.New("Invalid port range '" + p + "' in access-list rule")
+	}
+	m[pfx+"-range"] = l
+	m[pfx+"-port-end"] = h
+	return nil
+}
+
+// aclRulePayload builds the aXAPI 'rules' object for an ACLRule
+//-----------------------------------------------------------------------------
+func aclRulePayload(r ACLRule) (map[string]interface{}, error) {
+	r = r.Normalize()
+	if r.Seq <= 0 {
+		return nil, errors.New("access-list rule has no sequence number")
+	}
+	if r.Action != "permit" && r.Action != "deny" {
+		return nil, errors.New("Invalid access-list rule action '" + r.Action + "'")
+	}
+	m := map[string]interface{}{"seq-num": r.Seq, "action": r.Action}
+	switch r.Protocol {
+	case "ip", "tcp", "udp", "icmp":
+		m[r.Protocol] = 1
+	default:
+		return nil, errors.New("Invalid access-list rule protocol '" + r.Protocol + "'")
+	}
+	if r.Remark != "" {
+		m["remark"] = r.Remark
+	}
+	if err := aclAddr(m, "src", r.Src); err != nil {
+		return nil, err
+	}
+	if err := aclAddr(m, "dst", r.Dst); err != nil {
+		return nil, err
+	}
+	if err := aclPort(m, "src", r.SrcPort); err != nil {
+		return nil, err
+	}
+	if err := aclPort(m, "dst", r.DstPort); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseACLRule pulls an ACLRule out of a 'rules' JSON object
+//-----------------------------------------------------------------------------
+func parseACLRule(v gjson.Result) ACLRule {
+	r := ACLRule{Seq: int(v.Get("seq-num").Int()), Action: v.Get("action").Str, Remark: v.Get("remark").Str}
+	for _, p := range []string{"ip", "tcp", "udp", "icmp"} {
+		if v.Get(p).Int() == 1 {
+			r.Protocol = p
+		}
+	}
+	addr := func(pfx string) string {
+		if h := v.Get(pfx + "-host").Str; h != "" {
+			return h
+		}
+		sn := net.ParseIP(v.Get(pfx + "-subnet").Str)
+		if sn == nil {
+			return "any"
+		}
+		mk := net.ParseIP(strings.TrimPrefix(v.Get(pfx+"-mask").Str, "/")).To4()
+		if mk == nil {
+			// a '/nn' prefix length
+			n, _ := strconv.Atoi(strings.TrimPrefix(v.Get(pfx+"-mask").Str, "/"))
+			return (&net.IPNet{IP: sn.Mask(net.CIDRMask(n, 32)), Mask: net.CIDRMask(n, 32)}).String()
+		}
+		// wildcard mask, unless it is already a subnet mask
+		m := net.IPMask{^mk[0], ^mk[1], ^mk[2], ^mk[3]}
+		if _, bits := m.Size(); bits == 0 {
+			m = net.IPMask(mk)
+		}
+		return (&net.IPNet{IP: sn.Mask(m), Mask: m}).String()
+	}
+	port := func(pfx string) string {
+		if e := v.Get(pfx + "-eq"); e.Exists() {
+			return e.String()
+		}
+		if lo := v.Get(pfx + "-range"); lo.Exists() {
+			return lo.String() + "-" + v.Get(pfx+"-port-end").String()
+		}
+		return ""
+	}
+	r.Src, r.Dst = addr("src"), addr("dst")
+	r.SrcPort, r.DstPort = port("src"), port("dst")
+	return r.Normalize()
+}
+
+// aclURL -- IE> /access-list/extended/150
+//-----------------------------------------------------------------------------
+func aclURL(id int) string {
+	return "/access-list/extended/" + strconv.Itoa(id)
+}
+
+// GetACL()
+//-----------------------------------------------------------------------------
+func (d Device) GetACL(id int) (ACL, error) {
+	return d.GetACLContext(context.Background(), id)
+}
+
+// GetACLContext -- GetACL() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetACLContext(ctx context.Context, id int) (ACL, error) {
+	body, err := d.sendJSON(ctx, "GET", aclURL(id), nil)
+	if err != nil {
+		return ACL{}, err
+	}
+	acl := ACL{ID: id}
+	for _, v := range gjson.GetBytes(body, "extended.rules").Array() {
+		acl.Rules = append(acl.Rules, parseACLRule(v))
+	}
+	return acl, nil
+}
+
+// CreateACL()
+//-----------------------------------------------------------------------------
+// Example:
+// ACL{ID: 150, Rules: []ACLRule{
+//     {Seq: 10, Action: "permit", Protocol: "tcp", Src: "10.0.0.0/8", Dst: "any", DstPort: "443"},
+//     {Seq: 20, Action: "deny", Src: "any", Dst: "any", Remark: "everything else"}}}
+func (d Device) CreateACL(acl ACL) error {
+	return d.CreateACLContext(context.Background(), acl)
+}
+
+// CreateACLContext -- CreateACL() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CreateACLContext(ctx context.Context, acl ACL) error {
+	var rl []map[string]interface{}
+	for _, r := range acl.Rules {
+		m, err := aclRulePayload(r)
+		if err != nil {
+			return err
+		}
+		rl = append(rl, m)
+	}
+	m := map[string]interface{}{"id": acl.ID}
+	if len(rl) > 0 {
+		m["rules"] = rl
+	}
+	_, err := d.sendJSON(ctx, "POST", "/access-list/extended", map[string]interface{}{"extended": m})
+	return err
+}
+
+// DeleteACL()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteACL(id int) error {
+	return d.DeleteACLContext(context.Background(), id)
+}
+
+// DeleteACLContext -- DeleteACL() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteACLContext(ctx context.Context, id int) error {
+	_, err := d.sendJSON(ctx, "DELETE", aclURL(id), nil)
+	return err
+}
+
+// SetACLRule()
+//-----------------------------------------------------------------------------
+// Adds a rule to an access-list, or replaces the rule already at its Seq. The
+// rest of the access-list is left alone.
+func (d Device) SetACLRule(id int, r ACLRule) error {
+	return d.SetACLRuleContext(context.Background(), id, r)
+}
+
+// SetACLRuleContext -- SetACLRule() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SetACLRuleContext(ctx context.Context, id int, r ACLRule) error {
+	m, err := aclRulePayload(r)
+	if err != nil {
+		return err
+	}
+	_, err = d.sendJSON(ctx, "PUT", aclURL(id)+"/rules/"+strconv.Itoa(r.Seq), map[string]interface{}{"rules": m})
+	if IsNotFound(err) {
+		_, err = d.sendJSON(ctx, "POST", aclURL(id)+"/rules", map[string]interface{}{"rules": m})
+	}
+	return err
+}
+
+// DeleteACLRule()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteACLRule(id int, seq int) error {
+	return d.DeleteACLRuleContext(context.Background(), id, seq)
+}
+
+// DeleteACLRuleContext -- DeleteACLRule() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteACLRuleContext(ctx context.Context, id int, seq int) error {
+	_, err := d.sendJSON(ctx, "DELETE", aclURL(id)+"/rules/"+strconv.Itoa(seq), nil)
+	return err
+}
+
+// BindACLToVirtualPort()
+//-----------------------------------------------------------------------------
+// Adds an access-list to a virtual-server port. Any other access-lists on the
+// port are kept.
+func (d Device) BindACLToVirtualPort(vs string, port int, proto string, id int) error {
+	return d.BindACLToVirtualPortContext(context.Background(), vs, port, proto, id)
+}
+
+// BindACLToVirtualPortContext -- BindACLToVirtualPort() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) BindACLToVirtualPortContext(ctx context.Context, vs string, port int, proto string, id int) error {
+	pl := map[string]interface{}{"port": map[string]interface{}{
+		"port-number": port,
+		"protocol":    proto,
+		"acl-list":    []map[string]int{{"acl-id": id}},
+	}}
+	_, err := d.sendJSON(ctx, "POST", vportURL(vs, port, proto), pl)
+	return err
+}
+
+// UnbindACLFromVirtualPort()
+//-----------------------------------------------------------------------------
+func (d Device) UnbindACLFromVirtualPort(vs string, port int, proto string, id int) error {
+	return d.UnbindACLFromVirtualPortContext(context.Background(), vs, port, proto, id)
+}
+
+// UnbindACLFromVirtualPortContext -- UnbindACLFromVirtualPort() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UnbindACLFromVirtualPortContext(ctx context.Context, vs string, port int, proto string, id int) error {
+	_, err := d.sendJSON(ctx, "DELETE", vportURL(vs, port, proto)+"/acl/"+strconv.Itoa(id), nil)
+	return err
+}
+
+// BindACLToInterface()
+//-----------------------------------------------------------------------------
+// Applies an access-list to the inbound traffic of an ethernet interface.
+func (d Device) BindACLToInterface(ifnum int, id int) error {
+	return d.BindACLToInterfaceContext(context.Background(), ifnum, id)
+}
+
+// BindACLToInterfaceContext -- BindACLToInterface() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) BindACLToInterfaceContext(ctx context.Context, ifnum int, id int) error {
+	pl := map[string]interface{}{"access-list": map[string]interface{}{"acl-id": id}}
+	_, err := d.sendJSON(ctx, "POST", "/interface/ethernet/"+strconv.Itoa(ifnum)+"/access-list", pl)
+	return err
+}
+
+// UnbindACLFromInterface()
+//-----------------------------------------------------------------------------
+func (d Device) UnbindACLFromInterface(ifnum int) error {
+	return d.UnbindACLFromInterfaceContext(context.Background(), ifnum)
+}
+
+// UnbindACLFromInterfaceContext -- UnbindACLFromInterface() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UnbindACLFromInterfaceContext(ctx context.Context, ifnum int) error {
+	_, err := d.sendJSON(ctx, "DELETE", "/interface/ethernet/"+strconv.Itoa(ifnum)+"/access-list", nil)
+	return err
+}
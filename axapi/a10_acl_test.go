@@ -0,0 +1,116 @@
+//
+//  a10_acl.go tests
+//
+
+package axapi
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestACLRuleRoundTrip(t *testing.T) {
+	r := ACLRule{Seq: 10, Action: "Permit", Protocol: "tcp", Src: "10.1.2.3/16", SrcPort: "1024-65535", Dst: "192.168.1.10/32", DstPort: "443", Remark: "web"}
+	m, err := aclRulePayload(r)
+	notErr(t, err)
+	assert(t, m["src-subnet"], "10.1.0.0")
+	assert(t, m["src-mask"], "0.0.255.255")
+	assert(t, m["dst-host"], "192.168.1.10")
+	assert(t, m["dst-eq"], 443)
+
+	// -- What comes back from the Thunder should match the normalized rule
+	back := parseACLRule(gjson.Parse(`{"seq-num": 10, "action": "permit", "tcp": 1, "remark": "web",
+		"src-subnet": "10.1.0.0", "src-mask": "0.0.255.255", "src-range": 1024, "src-port-end": 65535,
+		"dst-host": "192.168.1.10", "dst-eq": 443}`))
+	assert(t, back, r.Normalize())
+
+	_, err = aclRulePayload(ACLRule{Seq: 20, Action: "allow"})
+	isErr(t, err, "Invalid access-list rule action 'allow'")
+}
+
+func TestACLCalls(t *testing.T) {
+	d := setup()
+	acl := ACL{ID: 199, Rules: []ACLRule{
+		{Seq: 10, Action: "permit", Protocol: "tcp", Src: "10.0.0.0/8", Dst: "any", DstPort: "443"},
+		{Seq: 20, Action: "deny", Src: "any", Dst: "any"},
+	}}
+	err := d.CreateACL(acl)
+	notErr(t, err)
+	g, err := d.GetACL(acl.ID)
+	notErr(t, err)
+	assert(t, len(g.Rules), 2)
+	assert(t, g.Rules[0], acl.Rules[0].Normalize())
+
+	err = d.SetACLRule(acl.ID, ACLRule{Seq: 15, Action: "permit", Protocol: "icmp", Src: "any", Dst: "any", Remark: "ping"})
+	notErr(t, err)
+	err = d.DeleteACLRule(acl.ID, 20)
+	notErr(t, err)
+	g, err = d.GetACL(acl.ID)
+	notErr(t, err)
+	assert(t, len(g.Rules), 2)
+	assert(t, g.Rules[1].Remark, "ping")
+
+	err = d.DeleteACL(acl.ID)
+	notErr(t, err)
+}
+
+// aclSeqs -- IE> "X@5 A@10"
+func aclSeqs(rl []ACLRule) string {
+	var ss []string
+	for _, r := range rl {
+		ss = append(ss, r.Remark+"@"+strconv.Itoa(r.Seq))
+	}
+	return strings.Join(ss, " ")
+}
+
+func TestDiffACLRules(t *testing.T) {
+	// -- Rules are told apart by Dst; the Remark is just a label for the test
+	rule := func(name string, seq int) ACLRule {
+		return ACLRule{Seq: seq, Action: "permit", Protocol: "ip", Src: "any", Dst: "10.0.0." + strconv.Itoa(int(name[0])), Remark: name}
+	}
+	rules := func(spec string) []ACLRule {
+		var rl []ACLRule
+		for _, f := range strings.Fields(spec) {
+			p := strings.Split(f, "@")
+			seq := 0
+			if len(p) > 1 {
+				seq, _ = strconv.Atoi(p[1])
+			}
+			rl = append(rl, rule(p[0], seq))
+		}
+		return rl
+	}
+	tests := []struct {
+		name          string
+		cur, want     string
+		add, upd, del string
+	}{
+		{"unchanged", "A@10 B@20", "A B", "", "", ""},
+		{"new list", "", "A B", "A@10 B@20", "", ""},
+		{"insert at front", "A@10 B@20", "X A B", "X@5", "", ""},
+		{"insert in middle", "A@10 B@20", "A X Y B", "X@14 Y@17", "", ""},
+		{"append", "A@10 B@20", "A B X", "X@30", "", ""},
+		{"reorder", "A@10 B@20 C@30", "C A B", "C@5", "", "C@30"},
+		{"remove", "A@10 B@20 C@30", "A C", "", "", "B@20"},
+		{"no gap left", "A@1 B@2", "A X B", "A@10 X@20 B@30", "", "A@1 B@2"},
+	}
+	for _, tc := range tests {
+		add, upd, del, err := DiffACLRules(rules(tc.cur), rules(tc.want))
+		notErr(t, err)
+		if aclSeqs(add) != tc.add || aclSeqs(upd) != tc.upd || aclSeqs(del) != tc.del {
+			t.Errorf("%s: got add [%s] upd [%s] del [%s], want add [%s] upd [%s] del [%s]", tc.name,
+				aclSeqs(add), aclSeqs(upd), aclSeqs(del), tc.add, tc.upd, tc.del)
+		}
+	}
+
+	// -- A remark change is made in place
+	w := rules("A B")
+	w[1].Remark = "B2"
+	add, upd, del, err := DiffACLRules(rules("A@10 B@20"), w)
+	notErr(t, err)
+	assert(t, len(add)+len(del), 0)
+	assert(t, aclSeqs(upd), "B2@20")
+}
@@ -0,0 +1,191 @@
+//
+//  a10_aflex.go  --  aFleX Script related aXAPI API calls
+//
+//  John D. Allen
+//  Sr. Solutions Engineer
+//  A10 Networks, Inc.
+//
+//  Copyright A10 Networks (c) 2020, All Rights Reserved.
+//
+
+package axapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Aflex holds the state of an aFleX script on the Thunder
+type Aflex struct {
+	Name   string
+	Syntax string // "Check" when it passed the syntax check
+	Bound  int    // number of virtual ports it is bound to
+}
+
+// GetAflexList()
+//-----------------------------------------------------------------------------
+func (d Device) GetAflexList() ([]Aflex, error) {
+	return d.GetAflexListContext(context.Background())
+}
+
+// GetAflexListContext -- GetAflexList() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetAflexListContext(ctx context.Context) ([]Aflex, error) {
+	var al []Aflex
+	body, err := d.sendJSON(ctx, "GET", "/file/aflex/oper", nil)
+	if err != nil {
+		return al, err
+	}
+	list := gjson.GetBytes(body, "aflex.oper.file-list")
+	if !list.Exists() {
+		list = gjson.GetBytes(body, "aflex.oper.aflex-list")
+	}
+	for _, v := range list.Array() {
+		a := Aflex{Name: v.Get("file").Str, Syntax: v.Get("syntax").Str, Bound: int(v.Get("virtual-server").Int())}
+		if a.Name == "" {
+			a.Name = v.Get("name").Str
+		}
+		al = append(al, a)
+	}
+	return al, nil
+}
+
+// GetAflex()
+//-----------------------------------------------------------------------------
+func (d Device) GetAflex(name string) (Aflex, error) {
+	return d.GetAflexContext(context.Background(), name)
+}
+
+// GetAflexContext -- GetAflex() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetAflexContext(ctx context.Context, name string) (Aflex, error) {
+	al, err := d.GetAflexListContext(ctx)
+	if err != nil {
+		return Aflex{}, err
+	}
+	for _, a := range al {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return Aflex{}, &APIError{StatusCode: http.StatusNotFound, Message: "aFleX '" + name + "' does not exist", Method: "GET", Path: "/file/aflex/oper"}
+}
+
+// GetAflexScript()
+//-----------------------------------------------------------------------------
+// Returns the text of an aFleX script on the Thunder.
+func (d Device) GetAflexScript(name string) (string, error) {
+	return d.GetAflexScriptContext(context.Background(), name)
+}
+
+// GetAflexScriptContext -- GetAflexScript() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetAflexScriptContext(ctx context.Context, name string) (string, error) {
+	body, err := d.sendJSON(ctx, "GET", "/file/aflex/"+url.PathEscape(name), nil)
+	return string(body), err
+}
+
+// UploadAflex()
+//-----------------------------------------------------------------------------
+// Imports an aFleX script, overwriting any script of the same name. The Thunder
+// checks the syntax on import, and refuses a script that fails.
+func (d Device) UploadAflex(name string, script string) error {
+	return d.UploadAflexContext(context.Background(), name, script)
+}
+
+// UploadAflexContext -- UploadAflex() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UploadAflexContext(ctx context.Context, name string, script string) error {
+	js := map[string]interface{}{"aflex": map[string]string{"file": name, "file-handle": name, "action": "import"}}
+	_, err := d.sendFile(ctx, "/file/aflex", js, name, []byte(script))
+	return err
+}
+
+// UpdateAflex()
+//-----------------------------------------------------------------------------
+// Replaces the text of an existing aFleX script. Virtual ports it is bound to
+// pick up the new version for new connections.
+func (d Device) UpdateAflex(name string, script string) error {
+	return d.UpdateAflexContext(context.Background(), name, script)
+}
+
+// UpdateAflexContext -- UpdateAflex() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UpdateAflexContext(ctx context.Context, name string, script string) error {
+	if _, err := d.GetAflexContext(ctx, name); err != nil {
+		return err
+	}
+	return d.UploadAflexContext(ctx, name, script)
+}
+
+// DeleteAflex()
+//-----------------------------------------------------------------------------
+// The script must not be bound to any virtual ports.
+func (d Device) DeleteAflex(name string) error {
+	return d.DeleteAflexContext(context.Background(), name)
+}
+
+// DeleteAflexContext -- DeleteAflex() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteAflexContext(ctx context.Context, name string) error {
+	_, err := d.sendJSON(ctx, "POST", "/delete/aflex", map[string]interface{}{"aflex": map[string]string{"filename": name}})
+	return err
+}
+
+// CheckAflex()
+//-----------------------------------------------------------------------------
+// Returns an error if the aFleX script on the Thunder didn't pass the syntax check.
+func (d Device) CheckAflex(name string) error {
+	return d.CheckAflexContext(context.Background(), name)
+}
+
+// CheckAflexContext -- CheckAflex() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CheckAflexContext(ctx context.Context, name string) error {
+	a, err := d.GetAflexContext(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(a.Syntax, "Check") {
+		return errors.New("aFleX '" + name + "' failed the syntax check: " + a.Syntax)
+	}
+	return nil
+}
+
+// BindAflex()
+//-----------------------------------------------------------------------------
+// Adds an aFleX script to a virtual-server port. Any other scripts on the port
+// are kept.
+func (d Device) BindAflex(vs string, port int, proto string, name string) error {
+	return d.BindAflexContext(context.Background(), vs, port, proto, name)
+}
+
+// BindAflexContext -- BindAflex() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) BindAflexContext(ctx context.Context, vs string, port int, proto string, name string) error {
+	pl := map[string]interface{}{"port": map[string]interface{}{
+		"port-number":   port,
+		"protocol":      proto,
+		"aflex-scripts": []map[string]string{{"aflex": name}},
+	}}
+	_, err := d.sendJSON(ctx, "POST", vportURL(vs, port, proto), pl)
+	return err
+}
+
+// UnbindAflex()
+//-----------------------------------------------------------------------------
+func (d Device) UnbindAflex(vs string, port int, proto string, name string) error {
+	return d.UnbindAflexContext(context.Background(), vs, port, proto, name)
+}
+
+// UnbindAflexContext -- UnbindAflex() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UnbindAflexContext(ctx context.Context, vs string, port int, proto string, name string) error {
+	_, err := d.sendJSON(ctx, "DELETE", vportURL(vs, port, proto)+"/aflex-scripts/"+url.PathEscape(name), nil)
+	return err
+}
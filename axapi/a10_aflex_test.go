@@ -0,0 +1,46 @@
+//
+//  a10_aflex.go tests
+//
+
+package axapi
+
+import (
+	"strings"
+	"testing"
+)
+
+const testAflex = `when HTTP_REQUEST {
+  HTTP::redirect "https://[HTTP::host][HTTP::uri]"
+}
+`
+
+func TestAflexCalls(t *testing.T) {
+	d := setup()
+	name := "axapi-test-redirect"
+	err := d.UploadAflex(name, testAflex)
+	notErr(t, err)
+	err = d.CheckAflex(name)
+	notErr(t, err)
+	al, err := d.GetAflexList()
+	notErr(t, err)
+	found := false
+	for _, a := range al {
+		found = found || a.Name == name
+	}
+	assert(t, found, true)
+	txt, err := d.GetAflexScript(name)
+	notErr(t, err)
+	assert(t, strings.TrimSpace(txt), strings.TrimSpace(testAflex))
+
+	err = d.UpdateAflex(name, testAflex+"# v2\n")
+	notErr(t, err)
+
+	// -- A script with a syntax error is refused
+	err = d.UploadAflex("axapi-test-bad", "when HTTP_REQUEST {\n")
+	isErr(t, err, "bad aFleX was accepted")
+
+	err = d.DeleteAflex(name)
+	notErr(t, err)
+	_, err = d.GetAflex(name)
+	assert(t, IsNotFound(err), true)
+}
@@ -0,0 +1,295 @@
+//
+//  a10_classlist.go  --  Class-List related aXAPI API calls
+//
+//  John D. Allen
+//  Sr. Solutions Engineer
+//  A10 Networks, Inc.
+//
+//  Copyright A10 Networks (c) 2020, All Rights Reserved.
+//
+
+package axapi
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// ClassList holds a 'class-list'. Type is one of "ipv4", "ipv6", "string" or "ac"
+// (Aho-Corasick string matching).
+type ClassList struct {
+	Name    string
+	Type    string
+	Entries []ClassListEntry
+}
+
+// ClassListEntry is one line of a ClassList. Key is the address/prefix for the
+// "ipv4" & "ipv6" types, or the string to match for the "string" & "ac" types.
+type ClassListEntry struct {
+	Key   string
+	LID   int    // limit ID, 0 for none
+	Match string // "ac" only: "contains", "starts-with", "ends-with" or "equals"
+	Value string // "string" & "ac" only: optional value for the key
+}
+
+// clKeys are the aXAPI JSON names used by each class-list type
+type clKeys struct {
+	list  string // name of the entry list in the class-list object
+	path  string // entry path under /class-list/<name>/
+	key   string
+	lid   string
+	value string
+}
+
+var classListKeys = map[string]clKeys{
+	"ipv4":   {list: "ipv4-list", path: "ipv4", key: "ipv4addr", lid: "lid"},
+	"ipv6":   {list: "ipv6-list", path: "ipv6", key: "ipv6-addr", lid: "v6-lid"},
+	"string": {list: "str-list", path: "str", key: "str", lid: "str-lid", value: "value-str"},
+	"ac":     {list: "ac-list", path: "ac", key: "ac-key-string", lid: "ac-lid", value: "ac-value"},
+}
+
+// clPayload builds the aXAPI 'class-list' object for a ClassList
+//-----------------------------------------------------------------------------
+func clPayload(cl ClassList) (map[string]interface{}, error) {
+	k, ok := classListKeys[cl.Type]
+	if !ok {
+		return nil, errors.New("Invalid class-list type '" + cl.Type + "'")
+	}
+	var el []map[string]interface{}
+	for _, e := range cl.Entries {
+		em := map[string]interface{}{k.key: e.Key}
+		if e.LID != 0 {
+			em[k.lid] = e.LID
+		}
+		if e.Value != "" && k.value != "" {
+			em[k.value] = e.Value
+		}
+		if cl.Type == "ac" {
+			if e.Match == "" {
+				return nil, errors.New("No match type for class-list 'ac' entry '" + e.Key + "'")
+			}
+			em["ac-match-type"] = e.Match
+		}
+		el = append(el, em)
+	}
+	m := map[string]interface{}{"name": cl.Name, "type": cl.Type}
+	if len(el) > 0 {
+		m[k.list] = el
+	}
+	return map[string]interface{}{"class-list": m}, nil
+}
+
+// parseClassList pulls a ClassList out of a 'class-list' JSON object
+//-----------------------------------------------------------------------------
+func parseClassList(v gjson.Result) ClassList {
+	cl := ClassList{Name: v.Get("name").Str, Type: v.Get("type").Str}
+	for t, k := range classListKeys {
+		for _, e := range v.Get(k.list).Array() {
+			if cl.Type == "" {
+				cl.Type = t
+			}
+			ce := ClassListEntry{Key: e.Get(k.key).Str, LID: int(e.Get(k.lid).Int())}
+			if k.value != "" {
+				ce.Value = e.Get(k.value).Str
+			}
+			ce.Match = e.Get("ac-match-type").Str
+			cl.Entries = append(cl.Entries, ce)
+		}
+	}
+	return cl
+}
+
+// GetClassLists()
+//-----------------------------------------------------------------------------
+func (d Device) GetClassLists() ([]ClassList, error) {
+	return d.GetClassListsContext(context.Background())
+}
+
+// GetClassListsContext -- GetClassLists() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetClassListsContext(ctx context.Context) ([]ClassList, error) {
+	var cll []ClassList
+	body, err := d.sendJSON(ctx, "GET", "/class-list", nil)
+	if err != nil {
+		return cll, err
+	}
+	for _, v := range gjson.GetBytes(body, "class-list-list").Array() {
+		cll = append(cll, parseClassList(v))
+	}
+	return cll, nil
+}
+
+// GetClassList()
+//-----------------------------------------------------------------------------
+func (d Device) GetClassList(name string) (ClassList, error) {
+	return d.GetClassListContext(context.Background(), name)
+}
+
+// GetClassListContext -- GetClassList() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetClassListContext(ctx context.Context, name string) (ClassList, error) {
+	body, err := d.sendJSON(ctx, "GET", "/class-list/"+url.PathEscape(name), nil)
+	if err != nil {
+		return ClassList{}, err
+	}
+	return parseClassList(gjson.GetBytes(body, "class-list")), nil
+}
+
+// CreateClassList()
+//-----------------------------------------------------------------------------
+// Example:
+// ClassList{Name: "blocked", Type: "ipv4", Entries: []ClassListEntry{{Key: "10.1.0.0/16", LID: 1}}}
+// Lists of more than a few thousand entries are better sent with UploadClassList().
+func (d Device) CreateClassList(cl ClassList) error {
+	return d.CreateClassListContext(context.Background(), cl)
+}
+
+// CreateClassListContext -- CreateClassList() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CreateClassListContext(ctx context.Context, cl ClassList) error {
+	pl, err := clPayload(cl)
+	if err != nil {
+		return err
+	}
+	_, err = d.sendJSON(ctx, "POST", "/class-list", pl)
+	return err
+}
+
+// ReplaceClassList()
+//-----------------------------------------------------------------------------
+// Replaces all the entries of an existing class-list with those in cl.
+func (d Device) ReplaceClassList(cl ClassList) error {
+	return d.ReplaceClassListContext(context.Background(), cl)
+}
+
+// ReplaceClassListContext -- ReplaceClassList() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) ReplaceClassListContext(ctx context.Context, cl ClassList) error {
+	pl, err := clPayload(cl)
+	if err != nil {
+		return err
+	}
+	_, err = d.sendJSON(ctx, "PUT", "/class-list/"+url.PathEscape(cl.Name), pl)
+	return err
+}
+
+// DeleteClassList()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteClassList(name string) error {
+	return d.DeleteClassListContext(context.Background(), name)
+}
+
+// DeleteClassListContext -- DeleteClassList() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteClassListContext(ctx context.Context, name string) error {
+	_, err := d.sendJSON(ctx, "DELETE", "/class-list/"+url.PathEscape(name), nil)
+	return err
+}
+
+// AddClassListEntries()
+//-----------------------------------------------------------------------------
+// Adds the Entries in cl to an existing class-list (of the same Type), in one API
+// call. Entries already in the list have their LID & Value updated.
+func (d Device) AddClassListEntries(cl ClassList) error {
+	return d.AddClassListEntriesContext(context.Background(), cl)
+}
+
+// AddClassListEntriesContext -- AddClassListEntries() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) AddClassListEntriesContext(ctx context.Context, cl ClassList) error {
+	if len(cl.Entries) == 0 {
+		return nil
+	}
+	pl, err := clPayload(cl)
+	if err != nil {
+		return err
+	}
+	_, err = d.sendJSON(ctx, "POST", "/class-list/"+url.PathEscape(cl.Name), pl)
+	return err
+}
+
+// RemoveClassListEntries()
+//-----------------------------------------------------------------------------
+// Removes the Entries in cl from an existing class-list (of the same Type). Only
+// the Key (and Match, for "ac") of each entry is used. Entries that aren't in
+// the list are skipped.
+func (d Device) RemoveClassListEntries(cl ClassList) error {
+	return d.RemoveClassListEntriesContext(context.Background(), cl)
+}
+
+// RemoveClassListEntriesContext -- RemoveClassListEntries() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) RemoveClassListEntriesContext(ctx context.Context, cl ClassList) error {
+	k, ok := classListKeys[cl.Type]
+	if !ok {
+		return errors.New("Invalid class-list type '" + cl.Type + "'")
+	}
+	for _, e := range cl.Entries {
+		id := url.PathEscape(e.Key)
+		if cl.Type == "ac" {
+			id = e.Match + "+" + id
+		}
+		_, err := d.sendJSON(ctx, "DELETE", "/class-list/"+url.PathEscape(cl.Name)+"/"+k.path+"/"+id, nil)
+		if err != nil && !IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClassListFile renders a ClassList in the ACOS class-list file format
+//-----------------------------------------------------------------------------
+func ClassListFile(cl ClassList) (string, error) {
+	if _, ok := classListKeys[cl.Type]; !ok {
+		return "", errors.New("Invalid class-list type '" + cl.Type + "'")
+	}
+	var sb strings.Builder
+	sb.WriteString("class-list " + cl.Name + " " + cl.Type + " file\n")
+	for _, e := range cl.Entries {
+		switch cl.Type {
+		case "ipv4", "ipv6":
+			sb.WriteString(e.Key)
+		case "string":
+			sb.WriteString("str " + strconv.Quote(e.Key))
+			if e.Value != "" {
+				sb.WriteString(" value " + strconv.Quote(e.Value))
+			}
+		case "ac":
+			sb.WriteString(e.Match + " " + strconv.Quote(e.Key))
+			if e.Value != "" {
+				sb.WriteString(" value " + strconv.Quote(e.Value))
+			}
+		}
+		if e.LID != 0 {
+			sb.WriteString(" lid " + strconv.Itoa(e.LID))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// UploadClassList()
+//-----------------------------------------------------------------------------
+// Sends a class-list to the Thunder as a file, which is much quicker than the
+// JSON calls for large lists. An existing class-list file of the same name is
+// overwritten.
+func (d Device) UploadClassList(cl ClassList) error {
+	return d.UploadClassListContext(context.Background(), cl)
+}
+
+// UploadClassListContext -- UploadClassList() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UploadClassListContext(ctx context.Context, cl ClassList) error {
+	f, err := ClassListFile(cl)
+	if err != nil {
+		return err
+	}
+	js := map[string]interface{}{"class-list": map[string]string{"file": cl.Name, "file-handle": cl.Name, "action": "import"}}
+	_, err = d.sendFile(ctx, "/file/class-list", js, cl.Name, []byte(f))
+	return err
+}
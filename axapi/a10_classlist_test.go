@@ -0,0 +1,65 @@
+//
+//  a10_classlist.go tests
+//
+
+package axapi
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestClassListFile(t *testing.T) {
+	f, err := ClassListFile(ClassList{Name: "cl1", Type: "ac", Entries: []ClassListEntry{
+		{Key: "bad.example.com", Match: "ends-with", LID: 2},
+	}})
+	notErr(t, err)
+	assert(t, f, "class-list cl1 ac file\nends-with \"bad.example.com\" lid 2\n")
+	_, err = ClassListFile(ClassList{Name: "cl1", Type: "mac"})
+	isErr(t, err, "Invalid class-list type 'mac'")
+}
+
+func TestClassListCalls(t *testing.T) {
+	d := setup()
+	cl := ClassList{Name: "axapi-test-cl", Type: "ipv4", Entries: []ClassListEntry{
+		{Key: "10.99.0.0/16", LID: 1},
+		{Key: "10.98.1.1/32"},
+	}}
+	err := d.CreateClassList(cl)
+	notErr(t, err)
+	g, err := d.GetClassList(cl.Name)
+	notErr(t, err)
+	assert(t, g.Type, "ipv4")
+	assert(t, len(g.Entries), 2)
+
+	err = d.AddClassListEntries(ClassList{Name: cl.Name, Type: "ipv4", Entries: []ClassListEntry{{Key: "10.97.0.0/24"}}})
+	notErr(t, err)
+	err = d.RemoveClassListEntries(ClassList{Name: cl.Name, Type: "ipv4", Entries: []ClassListEntry{{Key: "10.98.1.1/32"}}})
+	notErr(t, err)
+	g, err = d.GetClassList(cl.Name)
+	notErr(t, err)
+	assert(t, len(g.Entries), 2)
+
+	err = d.ReplaceClassList(ClassList{Name: cl.Name, Type: "ipv4", Entries: []ClassListEntry{{Key: "10.96.0.0/24"}}})
+	notErr(t, err)
+	g, err = d.GetClassList(cl.Name)
+	notErr(t, err)
+	assert(t, len(g.Entries), 1)
+
+	err = d.DeleteClassList(cl.Name)
+	notErr(t, err)
+	_, err = d.GetClassList(cl.Name)
+	assert(t, IsNotFound(err), true)
+}
+
+func TestUploadClassList(t *testing.T) {
+	d := setup()
+	cl := ClassList{Name: "axapi-test-clf", Type: "string"}
+	for i := 0; i < 5000; i++ {
+		cl.Entries = append(cl.Entries, ClassListEntry{Key: "key-" + strconv.Itoa(i)})
+	}
+	err := d.UploadClassList(cl)
+	notErr(t, err)
+	err = d.DeleteClassList(cl.Name)
+	notErr(t, err)
+}
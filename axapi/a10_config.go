@@ -0,0 +1,84 @@
+//
+//  a10_config.go  --  Running & Startup Config related aXAPI API calls
+//
+//  John D. Allen
+//  Sr. Solutions Engineer
+//  A10 Networks, Inc.
+//
+//  Copyright A10 Networks (c) 2020, All Rights Reserved.
+//
+
+package axapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// GetRunningConfig()
+//-----------------------------------------------------------------------------
+// Returns the running-config of the active partition as text.
+func (d Device) GetRunningConfig() (string, error) {
+	return d.GetRunningConfigContext(context.Background())
+}
+
+// GetRunningConfigContext -- GetRunningConfig() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetRunningConfigContext(ctx context.Context) (string, error) {
+	return d.showConfig(ctx, "show running-config")
+}
+
+// GetStartupConfig()
+//-----------------------------------------------------------------------------
+// Returns the startup-config of the active partition as text.
+func (d Device) GetStartupConfig() (string, error) {
+	return d.GetStartupConfigContext(context.Background())
+}
+
+// GetStartupConfigContext -- GetStartupConfig() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetStartupConfigContext(ctx context.Context) (string, error) {
+	return d.showConfig(ctx, "show startup-config")
+}
+
+// showConfig runs a 'show ...-config' command, and checks it printed a config
+//-----------------------------------------------------------------------------
+func (d Device) showConfig(ctx context.Context, cmd string) (string, error) {
+	res, err := d.cliRun(ctx, []string{cmd})
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(res[0].Output) == "" {
+		return "", errors.New("No config returned by '" + cmd + "'")
+	}
+	return res[0].Output, nil
+}
+
+// RestoreConfig()
+//-----------------------------------------------------------------------------
+// Replays a saved config (as returned by GetRunningConfig()) in config mode. This
+// puts back anything that was changed or removed since the config was saved, but
+// does NOT remove objects that have been added since. The running-config is not
+// written to the startup-config.
+func (d Device) RestoreConfig(cfg string) error {
+	return d.RestoreConfigContext(context.Background(), cfg)
+}
+
+// RestoreConfigContext -- RestoreConfig() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) RestoreConfigContext(ctx context.Context, cfg string) error {
+	cmds := []string{"configure"}
+	for _, l := range strings.Split(strings.Replace(cfg, "\r\n", "\n", -1), "\n") {
+		t := strings.TrimSpace(l)
+		if t == "" || strings.HasPrefix(t, "!") || t == "end" {
+			continue
+		}
+		cmds = append(cmds, l)
+	}
+	_, err := d.CliBatchContext(ctx, append(cmds, "end"), false)
+	if err != nil {
+		return errors.New("Restoring config: " + err.Error())
+	}
+	return nil
+}
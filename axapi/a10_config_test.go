@@ -0,0 +1,23 @@
+//
+//  a10_config.go tests
+//
+
+package axapi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetConfig(t *testing.T) {
+	d := setup()
+	rc, err := d.GetRunningConfig()
+	notErr(t, err)
+	assert(t, strings.Contains(rc, "hostname"), true)
+	_, err = d.GetStartupConfig()
+	notErr(t, err)
+
+	// -- Replaying the running-config shouldn't change anything
+	err = d.RestoreConfig(rc)
+	notErr(t, err)
+}
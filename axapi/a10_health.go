@@ -0,0 +1,192 @@
+//
+//  a10_health.go  --  Health Monitor related aXAPI API calls
+//
+//  John D. Allen
+//  Sr. Solutions Engineer
+//  A10 Networks, Inc.
+//
+//  Copyright A10 Networks (c) 2020, All Rights Reserved.
+//
+
+package axapi
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/tidwall/gjson"
+)
+
+// HealthMonitor holds a 'health monitor' definition. Method is one of "http", "tcp" or "icmp".
+// Port is used by the "http" & "tcp" methods, URL & ExpectCode by the "http" method only.
+type HealthMonitor struct {
+	Name       string
+	Method     string
+	Port       int
+	URL        string
+	ExpectCode string
+	Interval   int
+	Timeout    int
+	Retry      int
+	UpRetry    int
+}
+
+// hmPayload builds the aXAPI JSON payload for a HealthMonitor
+//-----------------------------------------------------------------------------
+func hmPayload(hm HealthMonitor) (map[string]interface{}, error) {
+	if hm.Name == "" {
+		return nil, errors.New("HealthMonitor Name field not set")
+	}
+	m := map[string]interface{}{}
+	switch hm.Method {
+	case "http":
+		h := map[string]interface{}{"http": 1}
+		if hm.Port != 0 {
+			h["http-port"] = hm.Port
+		}
+		if hm.URL != "" {
+			h["http-url"] = 1
+			h["url-type"] = "GET"
+			h["url-path"] = hm.URL
+		}
+		if hm.ExpectCode != "" {
+			h["http-expect"] = 1
+			h["http-response-code"] = hm.ExpectCode
+		}
+		m["http"] = h
+	case "tcp":
+		t := map[string]interface{}{"method-tcp": 1}
+		if hm.Port != 0 {
+			t["tcp-port"] = hm.Port
+		}
+		m["tcp"] = t
+	case "icmp":
+		m["icmp"] = map[string]interface{}{"icmp": 1}
+	default:
+		return nil, errors.New("Invalid HealthMonitor Method: " + hm.Method)
+	}
+
+	mon := map[string]interface{}{"name": hm.Name, "method": m}
+	if hm.Interval != 0 {
+		mon["interval"] = hm.Interval
+	}
+	if hm.Timeout != 0 {
+		mon["timeout"] = hm.Timeout
+	}
+	if hm.Retry != 0 {
+		mon["retry"] = hm.Retry
+	}
+	if hm.UpRetry != 0 {
+		mon["up-retry"] = hm.UpRetry
+	}
+	return map[string]interface{}{"monitor": mon}, nil
+}
+
+// parseHealthMonitor pulls a HealthMonitor out of a 'monitor' JSON object
+//-----------------------------------------------------------------------------
+func parseHealthMonitor(v gjson.Result) HealthMonitor {
+	var hm HealthMonitor
+	hm.Name = v.Get("name").Str
+	hm.Interval = int(v.Get("interval").Int())
+	hm.Timeout = int(v.Get("timeout").Int())
+	hm.Retry = int(v.Get("retry").Int())
+	hm.UpRetry = int(v.Get("up-retry").Int())
+	switch {
+	case v.Get("method.http.http").Int() == 1:
+		hm.Method = "http"
+		hm.Port = int(v.Get("method.http.http-port").Int())
+		hm.URL = v.Get("method.http.url-path").Str
+		hm.ExpectCode = v.Get("method.http.http-response-code").Str
+	case v.Get("method.tcp.method-tcp").Int() == 1:
+		hm.Method = "tcp"
+		hm.Port = int(v.Get("method.tcp.tcp-port").Int())
+	case v.Get("method.icmp.icmp").Int() == 1:
+		hm.Method = "icmp"
+	}
+	return hm
+}
+
+// GetHealthMonitors - Get the list of health monitors on the Thunder device
+//-----------------------------------------------------------------------------
+func (d Device) GetHealthMonitors() ([]HealthMonitor, error) {
+	return d.GetHealthMonitorsContext(context.Background())
+}
+
+// GetHealthMonitorsContext -- GetHealthMonitors() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetHealthMonitorsContext(ctx context.Context) ([]HealthMonitor, error) {
+	var hl []HealthMonitor
+	body, err := d.sendJSON(ctx, "GET", "/health/monitor", nil)
+	if err != nil {
+		return hl, err
+	}
+
+	for _, v := range gjson.GetBytes(body, "monitor-list").Array() {
+		hl = append(hl, parseHealthMonitor(v))
+	}
+	return hl, nil
+}
+
+// GetHealthMonitor - Get a single health monitor by name
+//-----------------------------------------------------------------------------
+func (d Device) GetHealthMonitor(name string) (HealthMonitor, error) {
+	return d.GetHealthMonitorContext(context.Background(), name)
+}
+
+// GetHealthMonitorContext -- GetHealthMonitor() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetHealthMonitorContext(ctx context.Context, name string) (HealthMonitor, error) {
+	body, err := d.sendJSON(ctx, "GET", "/health/monitor/"+url.PathEscape(name), nil)
+	if err != nil {
+		return HealthMonitor{}, err
+	}
+	return parseHealthMonitor(gjson.GetBytes(body, "monitor")), nil
+}
+
+// CreateHealthMonitor - Create a new health monitor on the Thunder device
+//-----------------------------------------------------------------------------
+func (d Device) CreateHealthMonitor(hm HealthMonitor) error {
+	return d.CreateHealthMonitorContext(context.Background(), hm)
+}
+
+// CreateHealthMonitorContext -- CreateHealthMonitor() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CreateHealthMonitorContext(ctx context.Context, hm HealthMonitor) error {
+	payload, err := hmPayload(hm)
+	if err != nil {
+		return err
+	}
+	_, err = d.sendJSON(ctx, "POST", "/health/monitor", payload)
+	return err
+}
+
+// UpdateHealthMonitor - Replace an existing health monitor's settings
+//-----------------------------------------------------------------------------
+func (d Device) UpdateHealthMonitor(hm HealthMonitor) error {
+	return d.UpdateHealthMonitorContext(context.Background(), hm)
+}
+
+// UpdateHealthMonitorContext -- UpdateHealthMonitor() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UpdateHealthMonitorContext(ctx context.Context, hm HealthMonitor) error {
+	payload, err := hmPayload(hm)
+	if err != nil {
+		return err
+	}
+	_, err = d.sendJSON(ctx, "PUT", "/health/monitor/"+url.PathEscape(hm.Name), payload)
+	return err
+}
+
+// DeleteHealthMonitor - Remove a health monitor from the Thunder device
+//-----------------------------------------------------------------------------
+func (d Device) DeleteHealthMonitor(name string) error {
+	return d.DeleteHealthMonitorContext(context.Background(), name)
+}
+
+// DeleteHealthMonitorContext -- DeleteHealthMonitor() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteHealthMonitorContext(ctx context.Context, name string) error {
+	_, err := d.sendJSON(ctx, "DELETE", "/health/monitor/"+url.PathEscape(name), nil)
+	return err
+}
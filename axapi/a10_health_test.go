@@ -0,0 +1,46 @@
+//
+//  a10_health.go tests
+//
+
+package axapi
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetHealthMonitors(t *testing.T) {
+	d := setup()
+	f, err := d.GetHealthMonitors()
+	notErr(t, err)
+	fmt.Println(f)
+}
+
+func TestHealthMonitorCalls(t *testing.T) {
+	d := setup()
+	hm := HealthMonitor{Name: "axapi-test-hm", Method: "tcp", Port: 80, Interval: 10, Timeout: 3}
+	err := d.CreateHealthMonitor(hm)
+	notErr(t, err)
+	g, err := d.GetHealthMonitor(hm.Name)
+	notErr(t, err)
+	assert(t, g.Method, "tcp")
+	assert(t, g.Port, 80)
+	assert(t, g.Interval, 10)
+
+	hm.Method = "http"
+	hm.URL = "/health"
+	hm.ExpectCode = "200"
+	err = d.UpdateHealthMonitor(hm)
+	notErr(t, err)
+	g, err = d.GetHealthMonitor(hm.Name)
+	notErr(t, err)
+	assert(t, g.Method, "http")
+	assert(t, g.URL, "/health")
+
+	hm.Method = "bogus"
+	err = d.UpdateHealthMonitor(hm) // this SHOULD err
+	isErr(t, err, "Uncaught test for invalid Method field")
+
+	err = d.DeleteHealthMonitor(hm.Name)
+	notErr(t, err)
+}
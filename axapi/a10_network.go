@@ -11,6 +11,7 @@
 package axapi
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"strings"
@@ -44,8 +45,14 @@ type DNS struct {
 // GetMgmtIntInfo -- Get info on the Management interface config
 //-----------------------------------------------------------------------------
 func (d Device) GetMgmtIntInfo() (NetInterface, error) {
+	return d.GetMgmtIntInfoContext(context.Background())
+}
+
+// GetMgmtIntInfoContext -- GetMgmtIntInfo() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetMgmtIntInfoContext(ctx context.Context) (NetInterface, error) {
 	ni := NetInterface{}
-	body, err := _restCall(d, "/interface/management", "GET", nil)
+	body, err := _restCall(ctx, d, "/interface/management", "GET", nil)
 	if err != nil {
 		return ni, err
 	}
@@ -65,8 +72,14 @@ func (d Device) GetMgmtIntInfo() (NetInterface, error) {
 // GetIntInfo -- Get info on specified Network Interface
 //-----------------------------------------------------------------------------
 func (d Device) GetIntInfo(ni NetInterface) (NetInterface, error) {
+	return d.GetIntInfoContext(context.Background(), ni)
+}
+
+// GetIntInfoContext -- GetIntInfo() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetIntInfoContext(ctx context.Context, ni NetInterface) (NetInterface, error) {
 	url := "/interface/ethernet/" + strconv.Itoa(ni.IfNum)
-	body, err := _restCall(d, url, "GET", nil)
+	body, err := _restCall(ctx, d, url, "GET", nil)
 	if err != nil {
 		return ni, err
 	}
@@ -88,9 +101,15 @@ func (d Device) GetIntInfo(ni NetInterface) (NetInterface, error) {
 // EnableInt -- Enable the specified Network Interface
 //-----------------------------------------------------------------------------
 func (d Device) EnableInt(ni NetInterface) (NetInterface, error) {
+	return d.EnableIntContext(context.Background(), ni)
+}
+
+// EnableIntContext -- EnableInt() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) EnableIntContext(ctx context.Context, ni NetInterface) (NetInterface, error) {
 	url := "/interface/ethernet/" + strconv.Itoa(ni.IfNum)
 	payload := strings.NewReader("{ \"ethernet\": { \"ifnum\": " + strconv.Itoa(ni.IfNum) + ", \"action\": \"enable\" } }")
-	body, err := _restCall(d, url, "POST", payload)
+	body, err := _restCall(ctx, d, url, "POST", payload)
 	if err != nil {
 		return ni, err
 	}
@@ -106,9 +125,15 @@ func (d Device) EnableInt(ni NetInterface) (NetInterface, error) {
 // DisableInt -- Enable the specified Network Interface
 //-----------------------------------------------------------------------------
 func (d Device) DisableInt(ni NetInterface) (NetInterface, error) {
+	return d.DisableIntContext(context.Background(), ni)
+}
+
+// DisableIntContext -- DisableInt() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DisableIntContext(ctx context.Context, ni NetInterface) (NetInterface, error) {
 	url := "/interface/ethernet/" + strconv.Itoa(ni.IfNum)
 	payload := strings.NewReader("{ \"ethernet\": { \"ifnum\": " + strconv.Itoa(ni.IfNum) + ", \"action\": \"disable\" } }")
-	body, err := _restCall(d, url, "POST", payload)
+	body, err := _restCall(ctx, d, url, "POST", payload)
 	if err != nil {
 		return ni, err
 	}
@@ -124,6 +149,12 @@ func (d Device) DisableInt(ni NetInterface) (NetInterface, error) {
 // SetIntIPv4Address - Set an IPv4 Address on the specified Network Interface
 //-----------------------------------------------------------------------------
 func (d Device) SetIntIPv4Address(ni NetInterface) (NetInterface, error) {
+	return d.SetIntIPv4AddressContext(context.Background(), ni)
+}
+
+// SetIntIPv4AddressContext -- SetIntIPv4Address() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SetIntIPv4AddressContext(ctx context.Context, ni NetInterface) (NetInterface, error) {
 	if ni.IPv4Address == "" {
 		return ni, errors.New("IPv4Address field not set")
 	}
@@ -143,7 +174,7 @@ func (d Device) SetIntIPv4Address(ni NetInterface) (NetInterface, error) {
 			ni.IPv4Netmask + "\" } } } }")
 	}
 
-	body, err := _restCall(d, url, "POST", payload)
+	body, err := _restCall(ctx, d, url, "POST", payload)
 	if err != nil {
 		return ni, err
 	}
@@ -162,8 +193,14 @@ func (d Device) SetIntIPv4Address(ni NetInterface) (NetInterface, error) {
 // GetDNSinfo -- Gets all the DNS info from the Thunder device
 //-----------------------------------------------------------------------------
 func (d Device) GetDNSinfo() (DNS, error) {
+	return d.GetDNSinfoContext(context.Background())
+}
+
+// GetDNSinfoContext -- GetDNSinfo() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetDNSinfoContext(ctx context.Context) (DNS, error) {
 	dn := DNS{}
-	body, err := _restCall(d, "/ip/dns?detail=true", "GET", nil)
+	body, err := _restCall(ctx, d, "/ip/dns?detail=true", "GET", nil)
 	if err != nil {
 		return dn, err
 	}
@@ -183,8 +220,14 @@ func (d Device) GetDNSinfo() (DNS, error) {
 // SetPrimaryIPv4DNSserver --  Sets the Primary DNS server
 //-----------------------------------------------------------------------------
 func (d Device) SetPrimaryIPv4DNSserver(dn DNS) (DNS, error) {
+	return d.SetPrimaryIPv4DNSserverContext(context.Background(), dn)
+}
+
+// SetPrimaryIPv4DNSserverContext -- SetPrimaryIPv4DNSserver() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SetPrimaryIPv4DNSserverContext(ctx context.Context, dn DNS) (DNS, error) {
 	payload := strings.NewReader("{ \"primary\": { \"ip-v4-addr\": \"" + dn.PriIPv4 + "\" } }")
-	body, err := _restCall(d, "/ip/dns/primary", "POST", payload)
+	body, err := _restCall(ctx, d, "/ip/dns/primary", "POST", payload)
 	if err != nil {
 		return dn, err
 	}
@@ -217,8 +260,14 @@ func (d Device) SetPrimaryIPv4DNSserver(dn DNS) (DNS, error) {
 // SetSecondaryIPv4DNSserver --  Sets the Primary DNS server
 //-----------------------------------------------------------------------------
 func (d Device) SetSecondaryIPv4DNSserver(dn DNS) (DNS, error) {
+	return d.SetSecondaryIPv4DNSserverContext(context.Background(), dn)
+}
+
+// SetSecondaryIPv4DNSserverContext -- SetSecondaryIPv4DNSserver() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SetSecondaryIPv4DNSserverContext(ctx context.Context, dn DNS) (DNS, error) {
 	payload := strings.NewReader("{ \"secondary\": { \"ip-v4-addr\": \"" + dn.SecIPv4 + "\" } }")
-	body, err := _restCall(d, "/ip/dns/secondary", "POST", payload)
+	body, err := _restCall(ctx, d, "/ip/dns/secondary", "POST", payload)
 	if err != nil {
 		return dn, err
 	}
@@ -234,8 +283,14 @@ func (d Device) SetSecondaryIPv4DNSserver(dn DNS) (DNS, error) {
 // SetDNSSuffix -- Sets the DNS Search suffix
 //-----------------------------------------------------------------------------
 func (d Device) SetDNSSuffix(dn DNS) (DNS, error) {
+	return d.SetDNSSuffixContext(context.Background(), dn)
+}
+
+// SetDNSSuffixContext -- SetDNSSuffix() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SetDNSSuffixContext(ctx context.Context, dn DNS) (DNS, error) {
 	payload := strings.NewReader("{ \"suffix\": { \"domain-name\": \"" + dn.Suffix + "\" } }")
-	body, err := _restCall(d, "/ip/dns/suffix", "POST", payload)
+	body, err := _restCall(ctx, d, "/ip/dns/suffix", "POST", payload)
 	if err != nil {
 		return dn, err
 	}
@@ -11,6 +11,7 @@
 package axapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"strconv"
@@ -48,8 +49,14 @@ type PartitionList struct {
 // GetPartition - Get the info on the given partition
 //-----------------------------------------------------------------------------
 func (d Device) GetPartition(name string) (Partition, error) {
+	return d.GetPartitionContext(context.Background(), name)
+}
+
+// GetPartitionContext -- GetPartition() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetPartitionContext(ctx context.Context, name string) (Partition, error) {
 	p := new(Partition)
-	v, err := d.GetPartitionList()
+	v, err := d.GetPartitionListContext(ctx)
 	if err != nil {
 		return Partition{}, err
 	}
@@ -66,8 +73,14 @@ func (d Device) GetPartition(name string) (Partition, error) {
 // GetPartitionList - Get the list of partitions from the Thunder Device
 //-----------------------------------------------------------------------------
 func (d Device) GetPartitionList() (PartitionList, error) {
+	return d.GetPartitionListContext(context.Background())
+}
+
+// GetPartitionListContext -- GetPartitionList() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetPartitionListContext(ctx context.Context) (PartitionList, error) {
 	p := PartitionList{}
-	body, err := _restCall(d, "/partition-all/oper", "GET", nil)
+	body, err := _restCall(ctx, d, "/partition-all/oper", "GET", nil)
 	if err != nil {
 		return PartitionList{}, err
 	}
@@ -83,8 +96,14 @@ func (d Device) GetPartitionList() (PartitionList, error) {
 // GetAvailablePartitionIDs - grab the available id array(s)
 //-----------------------------------------------------------------------------
 func (d Device) GetAvailablePartitionIDs() ([]int, error) {
+	return d.GetAvailablePartitionIDsContext(context.Background())
+}
+
+// GetAvailablePartitionIDsContext -- GetAvailablePartitionIDs() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetAvailablePartitionIDsContext(ctx context.Context) ([]int, error) {
 	var rr []int
-	body, err := _restCall(d, "/partition-available-id/oper", "GET", nil)
+	body, err := _restCall(ctx, d, "/partition-available-id/oper", "GET", nil)
 	if err != nil {
 		return []int{}, err
 	}
@@ -105,8 +124,14 @@ func (d Device) GetAvailablePartitionIDs() ([]int, error) {
 // CreatePartition - Make a new Partition on the Thunder Device
 //-----------------------------------------------------------------------------
 func (d Device) CreatePartition(name string, t string) (int, error) {
+	return d.CreatePartitionContext(context.Background(), name, t)
+}
+
+// CreatePartitionContext -- CreatePartition() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CreatePartitionContext(ctx context.Context, name string, t string) (int, error) {
 	// Get the next available partition number
-	ids, err := d.GetAvailablePartitionIDs()
+	ids, err := d.GetAvailablePartitionIDsContext(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -120,7 +145,7 @@ func (d Device) CreatePartition(name string, t string) (int, error) {
 	}
 
 	//fmt.Println(payload)
-	body, err := _restCall(d, "/partition", "POST", payload)
+	body, err := _restCall(ctx, d, "/partition", "POST", payload)
 	if err != nil {
 		return 0, err
 	}
@@ -136,8 +161,14 @@ func (d Device) CreatePartition(name string, t string) (int, error) {
 // DeletePartition - remove the partition from the Thunder device
 //-----------------------------------------------------------------------------
 func (d Device) DeletePartition(name string) error {
+	return d.DeletePartitionContext(context.Background(), name)
+}
+
+// DeletePartitionContext -- DeletePartition() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeletePartitionContext(ctx context.Context, name string) error {
 	// lookup partition to make sure its there.
-	g, err := d.GetPartition(name)
+	g, err := d.GetPartitionContext(ctx, name)
 	if err != nil {
 		return err
 	}
@@ -145,7 +176,7 @@ func (d Device) DeletePartition(name string) error {
 
 	// First, "delete" the partition (IE> Mark it as "Not-Active")
 	url := "/partition/" + name
-	body, err := _restCall(d, url, "DELETE", nil)
+	body, err := _restCall(ctx, d, url, "DELETE", nil)
 	if err != nil {
 		return err
 	}
@@ -158,7 +189,7 @@ func (d Device) DeletePartition(name string) error {
 	//payload := strings.NewReader(" ")
 	payload := strings.NewReader("{ \"partition\": {\"partition-name\": \"" + name + "\", \"id\": \"" + strconv.Itoa(id) + "\"} }")
 
-	body, err = _restCall(d, "/delete/partition/", "POST", payload)
+	body, err = _restCall(ctx, d, "/delete/partition/", "POST", payload)
 	if err != nil {
 		return err
 	}
@@ -174,7 +205,13 @@ func (d Device) DeletePartition(name string) error {
 // GetActivePartition - What is the current Active Partition?
 //-----------------------------------------------------------------------------
 func (d Device) GetActivePartition() (string, error) {
-	body, err := _restCall(d, "/active-partition", "GET", nil)
+	return d.GetActivePartitionContext(context.Background())
+}
+
+// GetActivePartitionContext -- GetActivePartition() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetActivePartitionContext(ctx context.Context) (string, error) {
+	body, err := _restCall(ctx, d, "/active-partition", "GET", nil)
 	if err != nil {
 		return "", err
 	}
@@ -189,7 +226,13 @@ func (d Device) GetActivePartition() (string, error) {
 // GetMaxPartitions - How many Partitions can this Thunder Device support?
 //-----------------------------------------------------------------------------
 func (d Device) GetMaxPartitions() (int, error) {
-	body, err := _restCall(d, "/techreport/max-partitions", "GET", nil)
+	return d.GetMaxPartitionsContext(context.Background())
+}
+
+// GetMaxPartitionsContext -- GetMaxPartitions() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetMaxPartitionsContext(ctx context.Context) (int, error) {
+	body, err := _restCall(ctx, d, "/techreport/max-partitions", "GET", nil)
 	if err != nil {
 		return 0, err
 	}
@@ -200,3 +243,80 @@ func (d Device) GetMaxPartitions() (int, error) {
 
 	return int(gjson.GetBytes(body, "max-partitions.value").Int()), nil
 }
+
+// partitionPayload is the 'active-partition' object that switches to a partition
+//-----------------------------------------------------------------------------
+func partitionPayload(name string) map[string]interface{} {
+	if name == "" || name == "shared" {
+		return map[string]interface{}{"active-partition": map[string]interface{}{"shared": 1}}
+	}
+	return map[string]interface{}{"active-partition": map[string]interface{}{"curr_part_name": name}}
+}
+
+// SetActivePartition - Switch the session to the given partition ("shared" for
+// the shared partition). All later API calls on the session, from any copy of
+// the Device, work in that partition.
+//-----------------------------------------------------------------------------
+func (d Device) SetActivePartition(name string) error {
+	return d.SetActivePartitionContext(context.Background(), name)
+}
+
+// SetActivePartitionContext -- SetActivePartition() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SetActivePartitionContext(ctx context.Context, name string) error {
+	_, err := d.sendJSON(ctx, "POST", "/active-partition", partitionPayload(name))
+	if err != nil {
+		return err
+	}
+	if d.session != nil {
+		if name == "shared" {
+			name = ""
+		}
+		d.session.mu.Lock()
+		d.session.partition = name
+		d.session.mu.Unlock()
+	}
+	return nil
+}
+
+// WithPartition - Run fn with the session switched to the given partition, then
+// switch back to the partition that was active before, even if fn fails.
+// Only one WithPartition() runs at a time on a session, so they must not be
+// nested -- and any other calls made on the session while fn runs will also
+// be in the partition.
+//-----------------------------------------------------------------------------
+func (d Device) WithPartition(name string, fn func(d Device) error) error {
+	return d.WithPartitionContext(context.Background(), name, fn)
+}
+
+// WithPartitionContext -- WithPartition() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) WithPartitionContext(ctx context.Context, name string, fn func(d Device) error) error {
+	var prev string
+	if d.session != nil {
+		d.session.part.Lock()
+		defer d.session.part.Unlock()
+		d.session.mu.Lock()
+		prev = d.session.partition
+		d.session.mu.Unlock()
+	} else {
+		var err error
+		if prev, err = d.GetActivePartitionContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := d.SetActivePartitionContext(ctx, name); err != nil {
+		return err
+	}
+	pd := d
+	pd.Partition = name
+	err := fn(pd)
+	if rerr := d.SetActivePartitionContext(ctx, prev); rerr != nil {
+		if err != nil {
+			return errors.New(err.Error() + "; and restoring partition '" + prev + "' failed: " + rerr.Error())
+		}
+		return rerr
+	}
+	return err
+}
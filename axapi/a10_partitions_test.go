@@ -49,3 +49,24 @@ func TestGetMaxPartitions(t *testing.T) {
 	notErr(t, err)
 	assertNot(t, f, 0)
 }
+
+func TestWithPartition(t *testing.T) {
+	name := "ptest"
+	d := setup()
+	_, err := d.CreatePartition(name, "adc")
+	notErr(t, err)
+	defer d.DeletePartition(name)
+
+	err = d.WithPartition(name, func(pd Device) error {
+		f, err := pd.GetActivePartition()
+		notErr(t, err)
+		assert(t, f, name)
+		assert(t, pd.Partition, name)
+		return nil
+	})
+	notErr(t, err)
+
+	f, err := d.GetActivePartition()
+	notErr(t, err)
+	assert(t, f, "shared")
+}
@@ -11,6 +11,9 @@
 package axapi
 
 import (
+	"context"
+	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/tidwall/gjson"
@@ -21,17 +24,34 @@ import (
 type Server struct {
 	Name            string
 	Host            string
-	Status          string
-	Template        string
+	Status          string // "enable" or "disable"
+	Template        string // template server
 	ConnectionLimit uint64
 	Weight          int
+	Ports           []ServerPort
+}
+
+// ServerPort is a port-list entry on an 'slb server'
+type ServerPort struct {
+	PortNumber int
+	Protocol   string // "tcp" or "udp"
+	Status     string // "enable" or "disable"
+	Template   string // template port
+	ConnLimit  uint64
+	Weight     int
 }
 
 // GetSLBservers()
 //-----------------------------------------------------------------------------
 func (d Device) GetSLBservers() ([]Server, error) {
+	return d.GetSLBserversContext(context.Background())
+}
+
+// GetSLBserversContext -- GetSLBservers() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetSLBserversContext(ctx context.Context) ([]Server, error) {
 	var s []Server
-	body, err := _restCall(d, "/slb/server", "GET", nil)
+	body, err := _restCall(ctx, d, "/slb/server", "GET", nil)
 	if err != nil {
 		return s, err
 	}
@@ -40,19 +60,168 @@ func (d Device) GetSLBservers() ([]Server, error) {
 	}
 
 	for _, v := range gjson.GetBytes(body, "server-list").Array() {
-		var x Server
-		x.Name = gjson.Get(v.String(), "name").Str
-		x.Host = gjson.Get(v.String(), "host").Str
-		x.Status = gjson.Get(v.String(), "action").Str
-		x.Template = gjson.Get(v.String(), "template-server").Str
-		x.ConnectionLimit = gjson.Get(v.String(), "conn-limit").Uint()
-		x.Weight = int(gjson.Get(v.String(), "weight").Int())
-		s = append(s, x)
+		s = append(s, parseServer(v))
 	}
 
 	return s, nil
 }
 
+// parseServer pulls a Server out of a 'server' JSON object
+//-----------------------------------------------------------------------------
+func parseServer(v gjson.Result) Server {
+	var x Server
+	x.Name = gjson.Get(v.String(), "name").Str
+	x.Host = gjson.Get(v.String(), "host").Str
+	x.Status = gjson.Get(v.String(), "action").Str
+	x.Template = gjson.Get(v.String(), "template-server").Str
+	x.ConnectionLimit = gjson.Get(v.String(), "conn-limit").Uint()
+	x.Weight = int(gjson.Get(v.String(), "weight").Int())
+	for _, z := range gjson.Get(v.String(), "port-list").Array() {
+		var p ServerPort
+		p.PortNumber = int(gjson.Get(z.String(), "port-number").Int())
+		p.Protocol = gjson.Get(z.String(), "protocol").Str
+		p.Status = gjson.Get(z.String(), "action").Str
+		p.Template = gjson.Get(z.String(), "template-port").Str
+		p.ConnLimit = gjson.Get(z.String(), "conn-limit").Uint()
+		p.Weight = int(gjson.Get(z.String(), "weight").Int())
+		x.Ports = append(x.Ports, p)
+	}
+	return x
+}
+
+// serverPayload builds the aXAPI 'server' object for a Server. Unset fields are
+// left out, so the Thunder keeps (or defaults) them.
+//-----------------------------------------------------------------------------
+func serverPayload(s Server) map[string]interface{} {
+	m := map[string]interface{}{"name": s.Name}
+	if s.Host != "" {
+		m["host"] = s.Host
+	}
+	if s.Status != "" {
+		m["action"] = s.Status
+	}
+	if s.Template != "" {
+		m["template-server"] = s.Template
+	}
+	if s.ConnectionLimit != 0 {
+		m["conn-limit"] = s.ConnectionLimit
+	}
+	if s.Weight != 0 {
+		m["weight"] = s.Weight
+	}
+	if len(s.Ports) > 0 {
+		var pl []map[string]interface{}
+		for _, p := range s.Ports {
+			pm := map[string]interface{}{"port-number": p.PortNumber, "protocol": p.Protocol}
+			if p.Status != "" {
+				pm["action"] = p.Status
+			}
+			if p.Template != "" {
+				pm["template-port"] = p.Template
+			}
+			if p.ConnLimit != 0 {
+				pm["conn-limit"] = p.ConnLimit
+			}
+			if p.Weight != 0 {
+				pm["weight"] = p.Weight
+			}
+			pl = append(pl, pm)
+		}
+		m["port-list"] = pl
+	}
+	return map[string]interface{}{"server": m}
+}
+
+// GetSLBserver()
+//-----------------------------------------------------------------------------
+func (d Device) GetSLBserver(name string) (Server, error) {
+	return d.GetSLBserverContext(context.Background(), name)
+}
+
+// GetSLBserverContext -- GetSLBserver() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetSLBserverContext(ctx context.Context, name string) (Server, error) {
+	body, err := d.sendJSON(ctx, "GET", "/slb/server/"+url.PathEscape(name), nil)
+	if err != nil {
+		return Server{}, err
+	}
+	return parseServer(gjson.GetBytes(body, "server")), nil
+}
+
+// CreateSLBserver()
+//-----------------------------------------------------------------------------
+// Needs at least the Name & Host set. Any Ports are created along with the server.
+// Example:
+// Server{Name: "web1", Host: "44.147.45.220", Template: "opa-policy-bw",
+//     Ports: []ServerPort{{PortNumber: 31721, Protocol: "tcp"}}}
+func (d Device) CreateSLBserver(s Server) error {
+	return d.CreateSLBserverContext(context.Background(), s)
+}
+
+// CreateSLBserverContext -- CreateSLBserver() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CreateSLBserverContext(ctx context.Context, s Server) error {
+	_, err := d.sendJSON(ctx, "POST", "/slb/server", serverPayload(s))
+	return err
+}
+
+// UpdateSLBserver()
+//-----------------------------------------------------------------------------
+// Only adds/updates the fields that are set, retaining all other values. Ports
+// listed are added, or updated if they already exist -- use DeleteSLBserverPort()
+// to remove one.
+func (d Device) UpdateSLBserver(s Server) error {
+	return d.UpdateSLBserverContext(context.Background(), s)
+}
+
+// UpdateSLBserverContext -- UpdateSLBserver() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UpdateSLBserverContext(ctx context.Context, s Server) error {
+	_, err := d.sendJSON(ctx, "POST", "/slb/server/"+url.PathEscape(s.Name), serverPayload(s))
+	return err
+}
+
+// SetSLBserverState()
+//-----------------------------------------------------------------------------
+// Sets the 'action' of a real server, taking it in or out of service. 'state'
+// should be either "enable" or "disable".
+func (d Device) SetSLBserverState(name string, state string) error {
+	return d.SetSLBserverStateContext(context.Background(), name, state)
+}
+
+// SetSLBserverStateContext -- SetSLBserverState() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SetSLBserverStateContext(ctx context.Context, name string, state string) error {
+	_, err := d.sendJSON(ctx, "POST", "/slb/server/"+url.PathEscape(name), map[string]interface{}{"server": map[string]string{"name": name, "action": state}})
+	return err
+}
+
+// DeleteSLBserver()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteSLBserver(name string) error {
+	return d.DeleteSLBserverContext(context.Background(), name)
+}
+
+// DeleteSLBserverContext -- DeleteSLBserver() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteSLBserverContext(ctx context.Context, name string) error {
+	_, err := d.sendJSON(ctx, "DELETE", "/slb/server/"+url.PathEscape(name), nil)
+	return err
+}
+
+// DeleteSLBserverPort()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteSLBserverPort(name string, port int, proto string) error {
+	return d.DeleteSLBserverPortContext(context.Background(), name, port, proto)
+}
+
+// DeleteSLBserverPortContext -- DeleteSLBserverPort() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteSLBserverPortContext(ctx context.Context, name string, port int, proto string) error {
+	_, err := d.sendJSON(ctx, "DELETE", "/slb/server/"+url.PathEscape(name)+"/port/"+strconv.Itoa(port)+"+"+proto, nil)
+	return err
+}
+
 // GetServceGroups()
 //-----------------------------------------------------------------------------
 type Member struct {
@@ -71,8 +240,14 @@ type SvcGrp struct {
 }
 
 func (d Device) GetServiceGroups() ([]SvcGrp, error) {
+	return d.GetServiceGroupsContext(context.Background())
+}
+
+// GetServiceGroupsContext -- GetServiceGroups() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetServiceGroupsContext(ctx context.Context) ([]SvcGrp, error) {
 	var sg []SvcGrp
-	body, err := _restCall(d, "/slb/service-group-list", "GET", nil)
+	body, err := _restCall(ctx, d, "/slb/service-group-list", "GET", nil)
 	if err != nil {
 		return sg, err
 	}
@@ -81,47 +256,252 @@ func (d Device) GetServiceGroups() ([]SvcGrp, error) {
 	}
 
 	for _, v := range gjson.GetBytes(body, "service-group-list").Array() {
-		var x SvcGrp
-		x.Name = gjson.Get(v.String(), "name").Str
-		x.Protocol = gjson.Get(v.String(), "protocol").Str
-		x.LBMethod = gjson.Get(v.String(), "lb-method").Str
-		x.Healthcheck = gjson.Get(v.String(), "health-check").Str
-		for _, z := range gjson.Get(v.String(), "member-list").Array() {
-			var m Member
-			m.Name = gjson.Get(z.String(), "name").Str
-			m.Port = int(gjson.Get(z.String(), "port").Int())
-			m.State = gjson.Get(z.String(), "member-state").Str
-			m.Priority = int(gjson.Get(z.String(), "member-priority").Int())
-			x.Members = append(x.Members, m)
-		}
-		sg = append(sg, x)
+		sg = append(sg, parseSvcGrp(v))
 	}
 
 	return sg, nil
 }
 
+// parseSvcGrp pulls a SvcGrp out of a 'service-group' JSON object
+//-----------------------------------------------------------------------------
+func parseSvcGrp(v gjson.Result) SvcGrp {
+	var x SvcGrp
+	x.Name = gjson.Get(v.String(), "name").Str
+	x.Protocol = gjson.Get(v.String(), "protocol").Str
+	x.LBMethod = gjson.Get(v.String(), "lb-method").Str
+	x.Healthcheck = gjson.Get(v.String(), "health-check").Str
+	for _, z := range gjson.Get(v.String(), "member-list").Array() {
+		var m Member
+		m.Name = gjson.Get(z.String(), "name").Str
+		m.Port = int(gjson.Get(z.String(), "port").Int())
+		m.State = gjson.Get(z.String(), "member-state").Str
+		m.Priority = int(gjson.Get(z.String(), "member-priority").Int())
+		x.Members = append(x.Members, m)
+	}
+	return x
+}
+
+// memberPayload builds the aXAPI 'member' object for a Member
+//-----------------------------------------------------------------------------
+func memberPayload(m Member) map[string]interface{} {
+	pm := map[string]interface{}{"name": m.Name, "port": m.Port}
+	if m.State != "" {
+		pm["member-state"] = m.State
+	}
+	if m.Priority != 0 {
+		pm["member-priority"] = m.Priority
+	}
+	return pm
+}
+
+// svcGrpPayload builds the aXAPI 'service-group' object for a SvcGrp. Unset
+// fields are left out, so the Thunder keeps (or defaults) them.
+//-----------------------------------------------------------------------------
+func svcGrpPayload(sg SvcGrp) map[string]interface{} {
+	m := map[string]interface{}{"name": sg.Name}
+	if sg.Protocol != "" {
+		m["protocol"] = sg.Protocol
+	}
+	if sg.LBMethod != "" {
+		m["lb-method"] = sg.LBMethod
+	}
+	if sg.Healthcheck != "" {
+		m["health-check"] = sg.Healthcheck
+	}
+	if len(sg.Members) > 0 {
+		var ml []map[string]interface{}
+		for _, mm := range sg.Members {
+			ml = append(ml, memberPayload(mm))
+		}
+		m["member-list"] = ml
+	}
+	return map[string]interface{}{"service-group": m}
+}
+
+// memberURL -- IE> /slb/service-group/ws-sg/member/44.147.45.220+31721
+//-----------------------------------------------------------------------------
+func memberURL(sg string, name string, port int) string {
+	return "/slb/service-group/" + url.PathEscape(sg) + "/member/" + url.PathEscape(name) + "+" + strconv.Itoa(port)
+}
+
+// GetServiceGroup()
+//-----------------------------------------------------------------------------
+func (d Device) GetServiceGroup(name string) (SvcGrp, error) {
+	return d.GetServiceGroupContext(context.Background(), name)
+}
+
+// GetServiceGroupContext -- GetServiceGroup() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetServiceGroupContext(ctx context.Context, name string) (SvcGrp, error) {
+	body, err := d.sendJSON(ctx, "GET", "/slb/service-group/"+url.PathEscape(name), nil)
+	if err != nil {
+		return SvcGrp{}, err
+	}
+	return parseSvcGrp(gjson.GetBytes(body, "service-group")), nil
+}
+
+// CreateServiceGroup()
+//-----------------------------------------------------------------------------
+// Needs at least the Name & Protocol ("tcp" or "udp") set. Any Members are
+// added along with the service-group, and their 'slb server's must already exist.
+func (d Device) CreateServiceGroup(sg SvcGrp) error {
+	return d.CreateServiceGroupContext(context.Background(), sg)
+}
+
+// CreateServiceGroupContext -- CreateServiceGroup() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CreateServiceGroupContext(ctx context.Context, sg SvcGrp) error {
+	_, err := d.sendJSON(ctx, "POST", "/slb/service-group", svcGrpPayload(sg))
+	return err
+}
+
+// UpdateServiceGroup()
+//-----------------------------------------------------------------------------
+// Only adds/updates the fields that are set, retaining all other values.
+// Members listed are added, or updated if they are already in the group.
+func (d Device) UpdateServiceGroup(sg SvcGrp) error {
+	return d.UpdateServiceGroupContext(context.Background(), sg)
+}
+
+// UpdateServiceGroupContext -- UpdateServiceGroup() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UpdateServiceGroupContext(ctx context.Context, sg SvcGrp) error {
+	_, err := d.sendJSON(ctx, "POST", "/slb/service-group/"+url.PathEscape(sg.Name), svcGrpPayload(sg))
+	return err
+}
+
+// DeleteServiceGroup()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteServiceGroup(name string) error {
+	return d.DeleteServiceGroupContext(context.Background(), name)
+}
+
+// DeleteServiceGroupContext -- DeleteServiceGroup() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteServiceGroupContext(ctx context.Context, name string) error {
+	_, err := d.sendJSON(ctx, "DELETE", "/slb/service-group/"+url.PathEscape(name), nil)
+	return err
+}
+
+// AddServiceGroupMember()
+//-----------------------------------------------------------------------------
+// Adds a member (an 'slb server' name & port) to a service-group, or updates its
+// State ("enable" or "disable") & Priority if it is already there.
+func (d Device) AddServiceGroupMember(sg string, m Member) error {
+	return d.AddServiceGroupMemberContext(context.Background(), sg, m)
+}
+
+// AddServiceGroupMemberContext -- AddServiceGroupMember() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) AddServiceGroupMemberContext(ctx context.Context, sg string, m Member) error {
+	_, err := d.sendJSON(ctx, "POST", "/slb/service-group/"+url.PathEscape(sg)+"/member", map[string]interface{}{"member": memberPayload(m)})
+	return err
+}
+
+// DeleteServiceGroupMember()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteServiceGroupMember(sg string, name string, port int) error {
+	return d.DeleteServiceGroupMemberContext(context.Background(), sg, name, port)
+}
+
+// DeleteServiceGroupMemberContext -- DeleteServiceGroupMember() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteServiceGroupMemberContext(ctx context.Context, sg string, name string, port int) error {
+	_, err := d.sendJSON(ctx, "DELETE", memberURL(sg, name, port), nil)
+	return err
+}
+
+// SyncServiceGroupMembers()
+//-----------------------------------------------------------------------------
+// Makes the members of a service-group match 'want'. Members that are missing,
+// or whose State or Priority differ, are sent in a single call; members that
+// shouldn't be there are deleted one at a time. A Member with no State or
+// Priority set leaves that value alone. Returns the number of members that were
+// added/updated and removed.
+func (d Device) SyncServiceGroupMembers(sg string, want []Member) (int, int, error) {
+	return d.SyncServiceGroupMembersContext(context.Background(), sg, want)
+}
+
+// SyncServiceGroupMembersContext -- SyncServiceGroupMembers() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SyncServiceGroupMembersContext(ctx context.Context, sg string, want []Member) (int, int, error) {
+	cur, err := d.GetServiceGroupContext(ctx, sg)
+	if err != nil {
+		return 0, 0, err
+	}
+	have := map[string]Member{}
+	for _, m := range cur.Members {
+		have[m.Name+"+"+strconv.Itoa(m.Port)] = m
+	}
+
+	var upd []map[string]interface{}
+	keep := map[string]bool{}
+	for _, m := range want {
+		key := m.Name + "+" + strconv.Itoa(m.Port)
+		keep[key] = true
+		h, ok := have[key]
+		if ok && (m.State == "" || m.State == h.State) && (m.Priority == 0 || m.Priority == h.Priority) {
+			continue
+		}
+		upd = append(upd, memberPayload(m))
+	}
+	if len(upd) > 0 {
+		_, err = d.sendJSON(ctx, "POST", "/slb/service-group/"+url.PathEscape(sg)+"/member", map[string]interface{}{"member-list": upd})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	removed := 0
+	for key, m := range have {
+		if keep[key] {
+			continue
+		}
+		if _, err = d.sendJSON(ctx, "DELETE", memberURL(sg, m.Name, m.Port), nil); err != nil {
+			return len(upd), removed, err
+		}
+		removed++
+	}
+	return len(upd), removed, nil
+}
+
 // GetVSlist()
 //-----------------------------------------------------------------------------
 type Port struct {
-	PortNumber int
-	Protocol   string
-	ConnLimit  uint64
-	Status     string
-	AutoSNAT   int
-	SvcGrp     string
-	Throughput uint64
+	PortNumber   int
+	Protocol     string
+	ConnLimit    uint64
+	Status       string
+	AutoSNAT     int
+	SNATPool     string
+	SvcGrp       string
+	Throughput   uint64
+	ClientSSL    string // template client-ssl
+	ServerSSL    string // template server-ssl
+	TemplateHTTP string
+	TemplateTCP  string
+	TemplatePort string   // template virtual-port
+	ACLs         []int    // access-list IDs bound to the port (read only)
+	Aflex        []string // aFleX scripts bound to the port (read only)
 }
 
 type VS struct {
-	Name   string
-	IP     string
-	Status string
-	Ports  []Port
+	Name     string
+	IP       string
+	Status   string
+	Template string // template virtual-server
+	Ports    []Port
 }
 
 func (d Device) GetVSlist() ([]VS, error) {
+	return d.GetVSlistContext(context.Background())
+}
+
+// GetVSlistContext -- GetVSlist() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetVSlistContext(ctx context.Context) ([]VS, error) {
 	var vsl []VS
-	body, err := _restCall(d, "/slb/virtual-server-list", "GET", nil)
+	body, err := _restCall(ctx, d, "/slb/virtual-server-list", "GET", nil)
 	if err != nil {
 		return vsl, err
 	}
@@ -130,33 +510,214 @@ func (d Device) GetVSlist() ([]VS, error) {
 	}
 
 	for _, s := range gjson.GetBytes(body, "virtual-server-list").Array() {
-		var vs VS
-		vs.Name = gjson.Get(s.String(), "name").Str
-		vs.IP = gjson.Get(s.String(), "ip-address").Str
-		vs.Status = gjson.Get(s.String(), "enable-disable-action").Str
-		for _, v := range gjson.Get(s.String(), "port-list").Array() {
-			var p Port
-			p.PortNumber = int(gjson.Get(v.String(), "port-number").Int())
-			p.Protocol = gjson.Get(v.String(), "protocol").Str
-			p.ConnLimit = gjson.Get(v.String(), "conn-limit").Uint()
-			p.Status = gjson.Get(v.String(), "action").Str
-			p.AutoSNAT = int(gjson.Get(v.String(), "auto").Int())
-			p.SvcGrp = gjson.Get(v.String(), "service-group").Str
-			vs.Ports = append(vs.Ports, p)
-		}
-		vsl = append(vsl, vs)
+		vsl = append(vsl, parseVS(s))
 	}
 
 	return vsl, nil
 }
 
+// parseVS pulls a VS out of a 'virtual-server' JSON object
+//-----------------------------------------------------------------------------
+func parseVS(s gjson.Result) VS {
+	var vs VS
+	vs.Name = gjson.Get(s.String(), "name").Str
+	vs.IP = gjson.Get(s.String(), "ip-address").Str
+	vs.Status = gjson.Get(s.String(), "enable-disable-action").Str
+	vs.Template = gjson.Get(s.String(), "template-virtual-server").Str
+	for _, v := range gjson.Get(s.String(), "port-list").Array() {
+		vs.Ports = append(vs.Ports, parsePort(v))
+	}
+	return vs
+}
+
+// parsePort pulls a Port out of a virtual-server 'port' JSON object
+//-----------------------------------------------------------------------------
+func parsePort(v gjson.Result) Port {
+	var p Port
+	p.PortNumber = int(gjson.Get(v.String(), "port-number").Int())
+	p.Protocol = gjson.Get(v.String(), "protocol").Str
+	p.ConnLimit = gjson.Get(v.String(), "conn-limit").Uint()
+	p.Status = gjson.Get(v.String(), "action").Str
+	p.AutoSNAT = int(gjson.Get(v.String(), "auto").Int())
+	p.SNATPool = gjson.Get(v.String(), "pool").Str
+	p.SvcGrp = gjson.Get(v.String(), "service-group").Str
+	p.ClientSSL = gjson.Get(v.String(), "template-client-ssl").Str
+	p.ServerSSL = gjson.Get(v.String(), "template-server-ssl").Str
+	p.TemplateHTTP = gjson.Get(v.String(), "template-http").Str
+	p.TemplateTCP = gjson.Get(v.String(), "template-tcp").Str
+	p.TemplatePort = gjson.Get(v.String(), "template-virtual-port").Str
+	for _, a := range gjson.Get(v.String(), "acl-list").Array() {
+		p.ACLs = append(p.ACLs, int(a.Get("acl-id").Int()))
+	}
+	for _, a := range gjson.Get(v.String(), "aflex-scripts").Array() {
+		p.Aflex = append(p.Aflex, a.Get("aflex").Str)
+	}
+	return p
+}
+
+// portPayload builds the aXAPI virtual-server 'port' object for a Port. Unset
+// fields are left out, so an update doesn't clobber them.
+//-----------------------------------------------------------------------------
+func portPayload(p Port) map[string]interface{} {
+	m := map[string]interface{}{"port-number": p.PortNumber, "protocol": p.Protocol}
+	strs := map[string]string{
+		"action":                p.Status,
+		"pool":                  p.SNATPool,
+		"service-group":         p.SvcGrp,
+		"template-client-ssl":   p.ClientSSL,
+		"template-server-ssl":   p.ServerSSL,
+		"template-http":         p.TemplateHTTP,
+		"template-tcp":          p.TemplateTCP,
+		"template-virtual-port": p.TemplatePort,
+	}
+	for k, v := range strs {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	if p.ConnLimit != 0 {
+		m["conn-limit"] = p.ConnLimit
+	}
+	if p.AutoSNAT != 0 {
+		m["auto"] = p.AutoSNAT
+	}
+	return m
+}
+
+// vsPayload builds the aXAPI 'virtual-server' object for a VS. Unset fields are
+// left out, so an update doesn't clobber them.
+//-----------------------------------------------------------------------------
+func vsPayload(vs VS) map[string]interface{} {
+	m := map[string]interface{}{"name": vs.Name}
+	if vs.IP != "" {
+		m["ip-address"] = vs.IP
+	}
+	if vs.Status != "" {
+		m["enable-disable-action"] = vs.Status
+	}
+	if vs.Template != "" {
+		m["template-virtual-server"] = vs.Template
+	}
+	if len(vs.Ports) > 0 {
+		var pl []map[string]interface{}
+		for _, p := range vs.Ports {
+			pl = append(pl, portPayload(p))
+		}
+		m["port-list"] = pl
+	}
+	return map[string]interface{}{"virtual-server": m}
+}
+
+// vportURL -- IE> /slb/virtual-server/ws-vip/port/80+http
+//-----------------------------------------------------------------------------
+func vportURL(vs string, port int, proto string) string {
+	return "/slb/virtual-server/" + url.PathEscape(vs) + "/port/" + strconv.Itoa(port) + "+" + proto
+}
+
+// GetVirtualServer()
+//-----------------------------------------------------------------------------
+func (d Device) GetVirtualServer(name string) (VS, error) {
+	return d.GetVirtualServerContext(context.Background(), name)
+}
+
+// GetVirtualServerContext -- GetVirtualServer() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetVirtualServerContext(ctx context.Context, name string) (VS, error) {
+	body, err := d.sendJSON(ctx, "GET", "/slb/virtual-server/"+url.PathEscape(name), nil)
+	if err != nil {
+		return VS{}, err
+	}
+	return parseVS(gjson.GetBytes(body, "virtual-server")), nil
+}
+
+// CreateVirtualServer()
+//-----------------------------------------------------------------------------
+// Needs at least the Name & IP set. Any Ports are created along with the
+// virtual-server, and their service-groups & templates must already exist.
+// Example:
+// VS{Name: "ws-vip", IP: "44.147.45.44", Ports: []Port{
+//     {PortNumber: 80, Protocol: "http", AutoSNAT: 1, SvcGrp: "ws-sg"}}}
+func (d Device) CreateVirtualServer(vs VS) error {
+	return d.CreateVirtualServerContext(context.Background(), vs)
+}
+
+// CreateVirtualServerContext -- CreateVirtualServer() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CreateVirtualServerContext(ctx context.Context, vs VS) error {
+	_, err := d.sendJSON(ctx, "POST", "/slb/virtual-server", vsPayload(vs))
+	return err
+}
+
+// DeleteVirtualServer()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteVirtualServer(name string) error {
+	return d.DeleteVirtualServerContext(context.Background(), name)
+}
+
+// DeleteVirtualServerContext -- DeleteVirtualServer() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteVirtualServerContext(ctx context.Context, name string) error {
+	_, err := d.sendJSON(ctx, "DELETE", "/slb/virtual-server/"+url.PathEscape(name), nil)
+	return err
+}
+
+// GetVirtualPort()
+//-----------------------------------------------------------------------------
+func (d Device) GetVirtualPort(vs string, port int, proto string) (Port, error) {
+	return d.GetVirtualPortContext(context.Background(), vs, port, proto)
+}
+
+// GetVirtualPortContext -- GetVirtualPort() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetVirtualPortContext(ctx context.Context, vs string, port int, proto string) (Port, error) {
+	body, err := d.sendJSON(ctx, "GET", vportURL(vs, port, proto), nil)
+	if err != nil {
+		return Port{}, err
+	}
+	return parsePort(gjson.GetBytes(body, "port")), nil
+}
+
+// CreateVirtualPort()
+//-----------------------------------------------------------------------------
+// Adds a port to an existing virtual-server. Needs at least the PortNumber &
+// Protocol set.
+func (d Device) CreateVirtualPort(vs string, p Port) error {
+	return d.CreateVirtualPortContext(context.Background(), vs, p)
+}
+
+// CreateVirtualPortContext -- CreateVirtualPort() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CreateVirtualPortContext(ctx context.Context, vs string, p Port) error {
+	_, err := d.sendJSON(ctx, "POST", "/slb/virtual-server/"+url.PathEscape(vs)+"/port", map[string]interface{}{"port": portPayload(p)})
+	return err
+}
+
+// DeleteVirtualPort()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteVirtualPort(vs string, port int, proto string) error {
+	return d.DeleteVirtualPortContext(context.Background(), vs, port, proto)
+}
+
+// DeleteVirtualPortContext -- DeleteVirtualPort() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteVirtualPortContext(ctx context.Context, vs string, port int, proto string) error {
+	_, err := d.sendJSON(ctx, "DELETE", vportURL(vs, port, proto), nil)
+	return err
+}
+
 // GetVSThroughput()
 //-----------------------------------------------------------------------------
 func (d Device) GetVSThroughput(vs string) ([]Port, error) {
+	return d.GetVSThroughputContext(context.Background(), vs)
+}
+
+// GetVSThroughputContext -- GetVSThroughput() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetVSThroughputContext(ctx context.Context, vs string) ([]Port, error) {
 	// Throughput returned is in bps
 	var ports []Port
-	url := "/slb/virtual-server/" + vs + "/stats"
-	body, err := _restCall(d, url, "GET", nil)
+	path := "/slb/virtual-server/" + url.PathEscape(vs) + "/stats"
+	body, err := _restCall(ctx, d, path, "GET", nil)
 	if err != nil {
 		return ports, err
 	}
@@ -176,118 +737,184 @@ func (d Device) GetVSThroughput(vs string) ([]Port, error) {
 	return ports, nil
 }
 
+// ServerTemplate holds an 'slb template server'. Zero values are left out of the
+// payload, so the Thunder's defaults apply to them.
+type ServerTemplate struct {
+	Name                   string `json:"name"`
+	ConnLimit              int    `json:"conn-limit,omitempty"`
+	ConnLimitNoLogging     int    `json:"conn-limit-no-logging,omitempty"`
+	ConnRateLimit          int    `json:"conn-rate-limit,omitempty"`
+	RateInterval           string `json:"rate-interval,omitempty"` // "second" or "100ms"
+	ConnRateLimitNoLogging int    `json:"conn-rate-limit-no-logging,omitempty"`
+	DNSQueryInterval       int    `json:"dns-query-interval,omitempty"`
+	DNSFailInterval        int    `json:"dns-fail-interval,omitempty"`
+	DynamicServerPrefix    string `json:"dynamic-server-prefix,omitempty"`
+	ExtendedStats          int    `json:"extended-stats,omitempty"`
+	LogSelectionFailure    int    `json:"log-selection-failure,omitempty"`
+	HealthCheck            string `json:"health-check,omitempty"`
+	HealthCheckDisable     int    `json:"health-check-disable,omitempty"`
+	MaxDynamicServer       int    `json:"max-dynamic-server,omitempty"`
+	MinTTLRatio            int    `json:"min-ttl-ratio,omitempty"`
+	Weight                 int    `json:"weight,omitempty"`
+	SpoofingCache          int    `json:"spoofing-cache,omitempty"`
+	StatsDataAction        string `json:"stats-data-action,omitempty"` // "stats-data-enable" or "stats-data-disable"
+	SlowStart              int    `json:"slow-start,omitempty"`
+	BWRateLimitAcct        string `json:"bw-rate-limit-acct,omitempty"` // "to-server-only", "from-server-only" or "all"
+	BWRateLimit            int    `json:"bw-rate-limit,omitempty"`      // Kbps
+	BWRateLimitResume      int    `json:"bw-rate-limit-resume,omitempty"`
+	BWRateLimitDuration    int    `json:"bw-rate-limit-duration,omitempty"`
+	BWRateLimitNoLogging   int    `json:"bw-rate-limit-no-logging,omitempty"`
+}
+
+// VirtualServerTemplate holds an 'slb template virtual-server'. Zero values are
+// left out of the payload, so the Thunder's defaults apply to them.
+type VirtualServerTemplate struct {
+	Name                    string `json:"name"`
+	ConnLimit               int    `json:"conn-limit,omitempty"`
+	ConnLimitReset          int    `json:"conn-limit-reset,omitempty"`
+	ConnLimitNoLogging      int    `json:"conn-limit-no-logging,omitempty"`
+	ConnRateLimit           int    `json:"conn-rate-limit,omitempty"`
+	RateInterval            string `json:"rate-interval,omitempty"` // "second" or "100ms"
+	ConnRateLimitReset      int    `json:"conn-rate-limit-reset,omitempty"`
+	ConnRateLimitNoLogging  int    `json:"conn-rate-limit-no-logging,omitempty"`
+	ICMPRateLimit           int    `json:"icmp-rate-limit,omitempty"`
+	ICMPLockup              int    `json:"icmp-lockup,omitempty"`
+	ICMPLockupPeriod        int    `json:"icmp-lockup-period,omitempty"`
+	SubnetGratuitousARP     int    `json:"subnet-gratuitous-arp,omitempty"`
+	DisableWhenAllPortsDown int    `json:"disable-when-all-ports-down,omitempty"`
+	DisableWhenAnyPortDown  int    `json:"disable-when-any-port-down,omitempty"`
+}
+
 // GetServerTemplate()
 //-----------------------------------------------------------------------------
-func (d Device) GetServerTemplate(tpl string) (string, error) {
-	url := "/slb/template/server/" + tpl
-	body, err := _restCall(d, url, "GET", nil)
-	if err != nil {
-		return "", err
-	}
-	if e, msg := d.chkResp(body); e {
-		return "", msg
-	}
-	return string(body), err
+func (d Device) GetServerTemplate(tpl string) (ServerTemplate, error) {
+	return d.GetServerTemplateContext(context.Background(), tpl)
+}
+
+// GetServerTemplateContext -- GetServerTemplate() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetServerTemplateContext(ctx context.Context, tpl string) (ServerTemplate, error) {
+	var t ServerTemplate
+	err := d.getJSON(ctx, "/slb/template/server/"+url.PathEscape(tpl), "server", &t)
+	return t, err
 }
 
 // GetVirtualServerTemplate()
 //-----------------------------------------------------------------------------
-func (d Device) GetVirtualServerTemplate(tpl string) (string, error) {
-	url := "/slb/template/virtual-server/" + tpl
-	body, err := _restCall(d, url, "GET", nil)
-	if err != nil {
-		return "", err
-	}
-	if e, msg := d.chkResp(body); e {
-		return "", msg
-	}
-	return string(body), err
+func (d Device) GetVirtualServerTemplate(tpl string) (VirtualServerTemplate, error) {
+	return d.GetVirtualServerTemplateContext(context.Background(), tpl)
+}
+
+// GetVirtualServerTemplateContext -- GetVirtualServerTemplate() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetVirtualServerTemplateContext(ctx context.Context, tpl string) (VirtualServerTemplate, error) {
+	var t VirtualServerTemplate
+	err := d.getJSON(ctx, "/slb/template/virtual-server/"+url.PathEscape(tpl), "virtual-server", &t)
+	return t, err
 }
 
 // CreateServerTemplate()
 //-----------------------------------------------------------------------------
-func (d Device) CreateServerTemplate(payload string) error {
-	// Payload should have at least the 'name' field, and any attributes you want to set.
-	// Example:
-	// "server": {
-	//     "name": "test",
-	//     "conn-limit": 64000000,
-	//     "conn-limit-no-logging": 0,
-	//     "dns-query-interval": 10,
-	//     "dns-fail-interval": 30,
-	//     "dynamic-server-prefix": "DRS",
-	//     "extended-stats": 0,
-	//     "log-selection-failure": 0,
-	//     "health-check-disable": 0,
-	//     "max-dynamic-server": 255,
-	//     "min-ttl-ratio": 2,
-	//     "weight": 1,
-	//     "spoofing-cache": 0,
-	//     "stats-data-action": "stats-data-enable",
-	//     "slow-start": 0,
-	//     "bw-rate-limit-acct": "all",
-	//     "bw-rate-limit": 1000,
-	//     "bw-rate-limit-resume": 800,
-	//     "bw-rate-limit-duration": 20,
-	//     "bw-rate-limit-no-logging": 0
-	// }
-	url := "/slb/template/server"
-	pl := strings.NewReader(payload)
-	body, err := _restCall(d, url, "POST", pl)
-	if err != nil {
-		return err
-	}
-	if e, msg := d.chkResp(body); e {
-		return msg
-	}
-	return nil
+// The template needs at least the Name set, and any attributes you want to set.
+// Example:
+// ServerTemplate{Name: "test", BWRateLimit: 1000, BWRateLimitResume: 800, BWRateLimitDuration: 20}
+func (d Device) CreateServerTemplate(t ServerTemplate) error {
+	return d.CreateServerTemplateContext(context.Background(), t)
+}
+
+// CreateServerTemplateContext -- CreateServerTemplate() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CreateServerTemplateContext(ctx context.Context, t ServerTemplate) error {
+	_, err := d.sendJSON(ctx, "POST", "/slb/template/server", map[string]interface{}{"server": t})
+	return err
 }
 
 // UpdateServerTemplate()
 //-----------------------------------------------------------------------------
-func (d Device) UpdateServerTemplate(payload string) error {
-	// NOTE: The 'name' field MUST be a part of the payload!
-	url := "/slb/template/server"
-	pl := strings.NewReader(payload)
-	body, err := _restCall(d, url, "PUT", pl)
-	if err != nil {
-		return err
-	}
-	if e, msg := d.chkResp(body); e {
-		return msg
-	}
-	return nil
+// Replaces all the settings on the template named t.Name.
+func (d Device) UpdateServerTemplate(t ServerTemplate) error {
+	return d.UpdateServerTemplateContext(context.Background(), t)
+}
+
+// UpdateServerTemplateContext -- UpdateServerTemplate() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UpdateServerTemplateContext(ctx context.Context, t ServerTemplate) error {
+	_, err := d.sendJSON(ctx, "PUT", "/slb/template/server/"+url.PathEscape(t.Name), map[string]interface{}{"server": t})
+	return err
+}
+
+// DeleteServerTemplate()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteServerTemplate(tpl string) error {
+	return d.DeleteServerTemplateContext(context.Background(), tpl)
+}
+
+// DeleteServerTemplateContext -- DeleteServerTemplate() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteServerTemplateContext(ctx context.Context, tpl string) error {
+	_, err := d.sendJSON(ctx, "DELETE", "/slb/template/server/"+url.PathEscape(tpl), nil)
+	return err
 }
 
 // CreateVirtualServerTemplate()
 //-----------------------------------------------------------------------------
-// Payload will at least need the 'name' field, and any KVs you want to set.
+// The template needs at least the Name set, and any attributes you want to set.
 // Example:
-// "virtual-server": {
-// 	  "name": "test2",
-// 	  "conn-limit": 200,
-// 	  "conn-rate-limit": 200
-// }
-func (d Device) CreateVirtualServerTemplate(payload string) error {
-	url := "/slb/template/virtual-server"
-	pl := strings.NewReader(payload)
-	body, err := _restCall(d, url, "POST", pl)
-	if err != nil {
-		return err
-	}
-	if e, msg := d.chkResp(body); e {
-		return msg
-	}
-	return nil
+// VirtualServerTemplate{Name: "test2", ConnLimit: 5000, ConnLimitReset: 1, ConnRateLimit: 200, RateInterval: "100ms"}
+// RateInterval is either "second" (the default) or "100ms". The '...Reset' fields
+// reset connections over the limit instead of dropping them.
+func (d Device) CreateVirtualServerTemplate(t VirtualServerTemplate) error {
+	return d.CreateVirtualServerTemplateContext(context.Background(), t)
+}
+
+// CreateVirtualServerTemplateContext -- CreateVirtualServerTemplate() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CreateVirtualServerTemplateContext(ctx context.Context, t VirtualServerTemplate) error {
+	_, err := d.sendJSON(ctx, "POST", "/slb/template/virtual-server", map[string]interface{}{"virtual-server": t})
+	return err
 }
 
 // UpdateVirtualServerTemplate()
 //-----------------------------------------------------------------------------
-func (d Device) UpdateVirtualServerTemplate(payload string) error {
-	// NOTE: The 'name' field MUST be a part of the payload!
-	url := "/slb/template/virtual-server"
-	pl := strings.NewReader(payload)
-	body, err := _restCall(d, url, "PUT", pl)
+// Replaces all the settings on the template named t.Name.
+func (d Device) UpdateVirtualServerTemplate(t VirtualServerTemplate) error {
+	return d.UpdateVirtualServerTemplateContext(context.Background(), t)
+}
+
+// UpdateVirtualServerTemplateContext -- UpdateVirtualServerTemplate() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UpdateVirtualServerTemplateContext(ctx context.Context, t VirtualServerTemplate) error {
+	_, err := d.sendJSON(ctx, "PUT", "/slb/template/virtual-server/"+url.PathEscape(t.Name), map[string]interface{}{"virtual-server": t})
+	return err
+}
+
+// DeleteVirtualServerTemplate()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteVirtualServerTemplate(tpl string) error {
+	return d.DeleteVirtualServerTemplateContext(context.Background(), tpl)
+}
+
+// DeleteVirtualServerTemplateContext -- DeleteVirtualServerTemplate() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteVirtualServerTemplateContext(ctx context.Context, tpl string) error {
+	_, err := d.sendJSON(ctx, "DELETE", "/slb/template/virtual-server/"+url.PathEscape(tpl), nil)
+	return err
+}
+
+// UpdateVirtualServer()
+//-----------------------------------------------------------------------------
+// This function only adds/updates to a virtual-server. It will overwrite vaules
+// if they already exist, or add KV lines to the virtual-server config. It retains
+// all other vaules (unlike a PUT would.) See UpdateVirtualServerTyped() to send
+// a VS instead of a JSON payload.
+func (d Device) UpdateVirtualServer(vs string, payload string) error {
+	return d.UpdateVirtualServerContext(context.Background(), vs, payload)
+}
+
+// UpdateVirtualServerContext -- UpdateVirtualServer() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UpdateVirtualServerContext(ctx context.Context, vs string, payload string) error {
+	body, err := _restCall(ctx, d, "/slb/virtual-server/"+url.PathEscape(vs), "POST", strings.NewReader(payload))
 	if err != nil {
 		return err
 	}
@@ -297,15 +924,143 @@ func (d Device) UpdateVirtualServerTemplate(payload string) error {
 	return nil
 }
 
-// UpdateVirtualServer()
+// UpdateVirtualServerTyped()
 //-----------------------------------------------------------------------------
-// This function only adds/updates to a virtual-server. It will overwrite vaules
-// if they already exist, or add KV lines to the virtual-server config. It retains
-// all other vaules (unlike a PUT would.)
-func (d Device) UpdateVirtualServer(vs string, payload string) error {
-	url := "/slb/virtual-server/" + vs
-	pl := strings.NewReader(payload)
-	body, err := _restCall(d, url, "POST", pl)
+// UpdateVirtualServer() from a VS. Only the fields that are set are sent, so it
+// retains all other vaules. Any Ports listed are added, or updated the same way
+// UpdateVirtualPortTyped() does.
+// Example, to attach a virtual-server template:
+// VS{Name: "ws-vip", Template: "opa-policy-cps"}
+func (d Device) UpdateVirtualServerTyped(vs VS) error {
+	return d.UpdateVirtualServerTypedContext(context.Background(), vs)
+}
+
+// UpdateVirtualServerTypedContext -- UpdateVirtualServerTyped() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UpdateVirtualServerTypedContext(ctx context.Context, vs VS) error {
+	_, err := d.sendJSON(ctx, "POST", "/slb/virtual-server/"+url.PathEscape(vs.Name), vsPayload(vs))
+	return err
+}
+
+// SetVirtualServerState()
+//-----------------------------------------------------------------------------
+// Sets the 'enable-disable-action' of a virtual-server. 'state' should be one of
+// "enable", "disable", "disable-when-all-ports-down" or "disable-when-any-port-down".
+func (d Device) SetVirtualServerState(vs string, state string) error {
+	return d.SetVirtualServerStateContext(context.Background(), vs, state)
+}
+
+// SetVirtualServerStateContext -- SetVirtualServerState() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SetVirtualServerStateContext(ctx context.Context, vs string, state string) error {
+	pl := map[string]interface{}{"virtual-server": map[string]interface{}{"name": vs, "enable-disable-action": state}}
+	_, err := d.sendJSON(ctx, "POST", "/slb/virtual-server/"+url.PathEscape(vs), pl)
+	return err
+}
+
+// SetVirtualPortState()
+//-----------------------------------------------------------------------------
+// Enables or Disables a single port on a virtual-server. 'state' should be either
+// "enable" or "disable". The port is identified by its number & protocol, the
+// same way ACOS names it (IE> 80+http).
+func (d Device) SetVirtualPortState(vs string, port int, proto string, state string) error {
+	return d.SetVirtualPortStateContext(context.Background(), vs, port, proto, state)
+}
+
+// SetVirtualPortStateContext -- SetVirtualPortState() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SetVirtualPortStateContext(ctx context.Context, vs string, port int, proto string, state string) error {
+	pl := map[string]interface{}{"port": map[string]interface{}{"port-number": port, "protocol": proto, "action": state}}
+	_, err := d.sendJSON(ctx, "POST", vportURL(vs, port, proto), pl)
+	return err
+}
+
+// ClientSSLTemplate holds an 'slb template client-ssl'. 'Version' is the highest, and
+// 'DGVersion' the lowest, protocol version allowed:
+// 30 = SSLv3, 31 = TLSv1.0, 32 = TLSv1.1, 33 = TLSv1.2, 34 = TLSv1.3
+type ClientSSLTemplate struct {
+	Name      string            `json:"name"`
+	Version   int               `json:"version,omitempty"`
+	DGVersion int               `json:"dgversion,omitempty"`
+	Ciphers   []ClientSSLCipher `json:"cipher-without-prio-list,omitempty"`
+	Certs     []ClientSSLCert   `json:"certificate-list,omitempty"`
+}
+
+// ClientSSLCipher is one entry in a client-ssl template's cipher list
+type ClientSSLCipher struct {
+	Cipher string `json:"cipher-wo-prio"`
+}
+
+// ClientSSLCert is one cert & key pair in a client-ssl template
+type ClientSSLCert struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key,omitempty"`
+}
+
+// GetClientSSLTemplate()
+//-----------------------------------------------------------------------------
+func (d Device) GetClientSSLTemplate(tpl string) (ClientSSLTemplate, error) {
+	return d.GetClientSSLTemplateContext(context.Background(), tpl)
+}
+
+// GetClientSSLTemplateContext -- GetClientSSLTemplate() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetClientSSLTemplateContext(ctx context.Context, tpl string) (ClientSSLTemplate, error) {
+	var t ClientSSLTemplate
+	err := d.getJSON(ctx, "/slb/template/client-ssl/"+url.PathEscape(tpl), "client-ssl", &t)
+	return t, err
+}
+
+// CreateClientSSLTemplate()
+//-----------------------------------------------------------------------------
+// The template needs at least the Name set, and any attributes you want to set.
+// Example:
+// ClientSSLTemplate{Name: "test-tls", Version: 34, DGVersion: 33,
+//     Ciphers: []ClientSSLCipher{{"TLS1_ECDHE_RSA_AES_128_GCM_SHA256"}},
+//     Certs: []ClientSSLCert{{Cert: "test-cert", Key: "test-key"}}}
+func (d Device) CreateClientSSLTemplate(t ClientSSLTemplate) error {
+	return d.CreateClientSSLTemplateContext(context.Background(), t)
+}
+
+// CreateClientSSLTemplateContext -- CreateClientSSLTemplate() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CreateClientSSLTemplateContext(ctx context.Context, t ClientSSLTemplate) error {
+	_, err := d.sendJSON(ctx, "POST", "/slb/template/client-ssl", map[string]interface{}{"client-ssl": t})
+	return err
+}
+
+// UpdateClientSSLTemplate()
+//-----------------------------------------------------------------------------
+// Replaces all the settings on the template named t.Name.
+func (d Device) UpdateClientSSLTemplate(t ClientSSLTemplate) error {
+	return d.UpdateClientSSLTemplateContext(context.Background(), t)
+}
+
+// UpdateClientSSLTemplateContext -- UpdateClientSSLTemplate() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UpdateClientSSLTemplateContext(ctx context.Context, t ClientSSLTemplate) error {
+	_, err := d.sendJSON(ctx, "PUT", "/slb/template/client-ssl/"+url.PathEscape(t.Name), map[string]interface{}{"client-ssl": t})
+	return err
+}
+
+// UpdateVirtualPort()
+//-----------------------------------------------------------------------------
+// Adds/updates KVs on a single virtual-server port, retaining all other values,
+// the same way UpdateVirtualServer() does.
+// Example, to bind a client-ssl template to port 443:
+// "port": {
+//    "port-number": 443,
+//    "protocol": "https",
+//    "template-client-ssl": "test-tls"
+// }
+func (d Device) UpdateVirtualPort(vs string, port int, proto string, payload string) error {
+	return d.UpdateVirtualPortContext(context.Background(), vs, port, proto, payload)
+}
+
+// UpdateVirtualPortContext -- UpdateVirtualPort() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UpdateVirtualPortContext(ctx context.Context, vs string, port int, proto string, payload string) error {
+	body, err := _restCall(ctx, d, vportURL(vs, port, proto), "POST", strings.NewReader(payload))
 	if err != nil {
 		return err
 	}
@@ -314,3 +1069,35 @@ func (d Device) UpdateVirtualServer(vs string, payload string) error {
 	}
 	return nil
 }
+
+// UpdateVirtualPortTyped()
+//-----------------------------------------------------------------------------
+// UpdateVirtualPort() from a Port. Only the fields that are set are sent.
+// Example, to bind a client-ssl template to port 443:
+// Port{PortNumber: 443, Protocol: "https", ClientSSL: "test-tls"}
+func (d Device) UpdateVirtualPortTyped(vs string, p Port) error {
+	return d.UpdateVirtualPortTypedContext(context.Background(), vs, p)
+}
+
+// UpdateVirtualPortTypedContext -- UpdateVirtualPortTyped() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UpdateVirtualPortTypedContext(ctx context.Context, vs string, p Port) error {
+	_, err := d.sendJSON(ctx, "POST", vportURL(vs, p.PortNumber, p.Protocol), map[string]interface{}{"port": portPayload(p)})
+	return err
+}
+
+// SetServiceGroupHealthCheck()
+//-----------------------------------------------------------------------------
+// Binds a health monitor to a service-group. All other service-group values
+// are retained.
+func (d Device) SetServiceGroupHealthCheck(sg string, hm string) error {
+	return d.SetServiceGroupHealthCheckContext(context.Background(), sg, hm)
+}
+
+// SetServiceGroupHealthCheckContext -- SetServiceGroupHealthCheck() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SetServiceGroupHealthCheckContext(ctx context.Context, sg string, hm string) error {
+	pl := map[string]interface{}{"service-group": map[string]interface{}{"name": sg, "health-check": hm}}
+	_, err := d.sendJSON(ctx, "POST", "/slb/service-group/"+url.PathEscape(sg), pl)
+	return err
+}
@@ -0,0 +1,185 @@
+//
+//  a10_slb.go tests
+//
+
+package axapi
+
+import (
+	"testing"
+)
+
+func TestServerTemplateCalls(t *testing.T) {
+	d := setup()
+	tpl := ServerTemplate{Name: "axapi-test \"bw\"", BWRateLimit: 1000, BWRateLimitResume: 800, BWRateLimitDuration: 20}
+	err := d.CreateServerTemplate(tpl)
+	notErr(t, err)
+	g, err := d.GetServerTemplate(tpl.Name)
+	notErr(t, err)
+	assert(t, g.Name, tpl.Name)
+	assert(t, g.BWRateLimit, 1000)
+
+	tpl.BWRateLimit = 2000
+	err = d.UpdateServerTemplate(tpl)
+	notErr(t, err)
+	g, err = d.GetServerTemplate(tpl.Name)
+	notErr(t, err)
+	assert(t, g.BWRateLimit, 2000)
+
+	err = d.DeleteServerTemplate(tpl.Name)
+	notErr(t, err)
+	_, err = d.GetServerTemplate(tpl.Name)
+	assert(t, IsNotFound(err), true)
+}
+
+func TestVirtualServerTemplateCalls(t *testing.T) {
+	d := setup()
+	tpl := VirtualServerTemplate{Name: "axapi-test-cps", ConnLimit: 5000, ConnLimitReset: 1, ConnRateLimit: 200, RateInterval: "100ms"}
+	err := d.CreateVirtualServerTemplate(tpl)
+	notErr(t, err)
+	g, err := d.GetVirtualServerTemplate(tpl.Name)
+	notErr(t, err)
+	assert(t, g.ConnLimit, 5000)
+	assert(t, g.ConnRateLimit, 200)
+	assert(t, g.RateInterval, "100ms")
+
+	tpl.ConnRateLimit = 100
+	err = d.UpdateVirtualServerTemplate(tpl)
+	notErr(t, err)
+	g, err = d.GetVirtualServerTemplate(tpl.Name)
+	notErr(t, err)
+	assert(t, g.ConnRateLimit, 100)
+
+	err = d.DeleteVirtualServerTemplate(tpl.Name)
+	notErr(t, err)
+}
+
+func TestSLBserverCalls(t *testing.T) {
+	d := setup()
+	s := Server{Name: "axapi-test-srv", Host: "10.99.99.1", ConnectionLimit: 1000, Weight: 2,
+		Ports: []ServerPort{{PortNumber: 8080, Protocol: "tcp"}}}
+	err := d.CreateSLBserver(s)
+	notErr(t, err)
+	g, err := d.GetSLBserver(s.Name)
+	notErr(t, err)
+	assert(t, g.Host, "10.99.99.1")
+	assert(t, g.ConnectionLimit, uint64(1000))
+	assert(t, len(g.Ports), 1)
+
+	// -- Update should only touch what is set
+	err = d.UpdateSLBserver(Server{Name: s.Name, Weight: 5, Ports: []ServerPort{{PortNumber: 8443, Protocol: "tcp", ConnLimit: 50}}})
+	notErr(t, err)
+	g, err = d.GetSLBserver(s.Name)
+	notErr(t, err)
+	assert(t, g.Weight, 5)
+	assert(t, g.ConnectionLimit, uint64(1000))
+	assert(t, len(g.Ports), 2)
+
+	err = d.SetSLBserverState(s.Name, "disable")
+	notErr(t, err)
+	g, err = d.GetSLBserver(s.Name)
+	notErr(t, err)
+	assert(t, g.Status, "disable")
+
+	err = d.DeleteSLBserverPort(s.Name, 8443, "tcp")
+	notErr(t, err)
+	g, err = d.GetSLBserver(s.Name)
+	notErr(t, err)
+	assert(t, len(g.Ports), 1)
+
+	err = d.DeleteSLBserver(s.Name)
+	notErr(t, err)
+	_, err = d.GetSLBserver(s.Name)
+	assert(t, IsNotFound(err), true)
+}
+
+func TestServiceGroupCalls(t *testing.T) {
+	d := setup()
+	for _, h := range []string{"10.99.99.1", "10.99.99.2", "10.99.99.3"} {
+		err := d.CreateSLBserver(Server{Name: "axapi-test-" + h, Host: h, Ports: []ServerPort{{PortNumber: 8080, Protocol: "tcp"}}})
+		notErr(t, err)
+		defer d.DeleteSLBserver("axapi-test-" + h)
+	}
+
+	sg := SvcGrp{Name: "axapi-test-sg", Protocol: "tcp", LBMethod: "least-connection",
+		Members: []Member{{Name: "axapi-test-10.99.99.1", Port: 8080}}}
+	err := d.CreateServiceGroup(sg)
+	notErr(t, err)
+	g, err := d.GetServiceGroup(sg.Name)
+	notErr(t, err)
+	assert(t, g.LBMethod, "least-connection")
+	assert(t, len(g.Members), 1)
+
+	err = d.AddServiceGroupMember(sg.Name, Member{Name: "axapi-test-10.99.99.2", Port: 8080, State: "disable", Priority: 5})
+	notErr(t, err)
+	g, err = d.GetServiceGroup(sg.Name)
+	notErr(t, err)
+	assert(t, len(g.Members), 2)
+
+	err = d.DeleteServiceGroupMember(sg.Name, "axapi-test-10.99.99.2", 8080)
+	notErr(t, err)
+
+	// -- Sync: drop .1, add .2 & .3
+	add, rm, err := d.SyncServiceGroupMembers(sg.Name, []Member{
+		{Name: "axapi-test-10.99.99.2", Port: 8080},
+		{Name: "axapi-test-10.99.99.3", Port: 8080, Priority: 2},
+	})
+	notErr(t, err)
+	assert(t, add, 2)
+	assert(t, rm, 1)
+	g, err = d.GetServiceGroup(sg.Name)
+	notErr(t, err)
+	assert(t, len(g.Members), 2)
+
+	// -- Nothing to do the second time around
+	add, rm, err = d.SyncServiceGroupMembers(sg.Name, []Member{
+		{Name: "axapi-test-10.99.99.2", Port: 8080},
+		{Name: "axapi-test-10.99.99.3", Port: 8080, Priority: 2},
+	})
+	notErr(t, err)
+	assert(t, add, 0)
+	assert(t, rm, 0)
+
+	err = d.DeleteServiceGroup(sg.Name)
+	notErr(t, err)
+	_, err = d.GetServiceGroup(sg.Name)
+	assert(t, IsNotFound(err), true)
+}
+
+func TestVirtualServerCalls(t *testing.T) {
+	d := setup()
+	vs := VS{Name: "axapi-test-vip", IP: "10.99.98.1", Ports: []Port{{PortNumber: 80, Protocol: "tcp", AutoSNAT: 1}}}
+	err := d.CreateVirtualServer(vs)
+	notErr(t, err)
+	g, err := d.GetVirtualServer(vs.Name)
+	notErr(t, err)
+	assert(t, g.IP, "10.99.98.1")
+	assert(t, len(g.Ports), 1)
+	assert(t, g.Ports[0].AutoSNAT, 1)
+
+	err = d.CreateVirtualPort(vs.Name, Port{PortNumber: 8080, Protocol: "tcp", ConnLimit: 100})
+	notErr(t, err)
+
+	// -- Partial updates should leave the other values alone
+	err = d.UpdateVirtualPortTyped(vs.Name, Port{PortNumber: 80, Protocol: "tcp", ConnLimit: 500})
+	notErr(t, err)
+	p, err := d.GetVirtualPort(vs.Name, 80, "tcp")
+	notErr(t, err)
+	assert(t, p.ConnLimit, uint64(500))
+	assert(t, p.AutoSNAT, 1)
+
+	err = d.UpdateVirtualServerTyped(VS{Name: vs.Name, Status: "disable"})
+	notErr(t, err)
+	g, err = d.GetVirtualServer(vs.Name)
+	notErr(t, err)
+	assert(t, g.Status, "disable")
+	assert(t, g.IP, "10.99.98.1")
+	assert(t, len(g.Ports), 2)
+
+	err = d.DeleteVirtualPort(vs.Name, 8080, "tcp")
+	notErr(t, err)
+	_, err = d.GetVirtualPort(vs.Name, 8080, "tcp")
+	assert(t, IsNotFound(err), true)
+
+	err = d.DeleteVirtualServer(vs.Name)
+	notErr(t, err)
+}
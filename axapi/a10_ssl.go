@@ -0,0 +1,270 @@
+//
+//  a10_ssl.go  --  SSL Certificate & Key related aXAPI API calls
+//
+//  John D. Allen
+//  Sr. Solutions Engineer
+//  A10 Networks, Inc.
+//
+//  Copyright A10 Networks (c) 2020, All Rights Reserved.
+//
+
+package axapi
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// SSLCert holds what is known about an SSL certificate, either parsed from a
+// local PEM file, or listed on the Thunder.
+type SSLCert struct {
+	Name       string
+	Type       string // IE> "certificate", "certificate/key", "chain"
+	CommonName string
+	NotAfter   time.Time
+}
+
+// ExpiresWithin -- Does the certificate expire in the next 'days' days (or has
+// it already)? A cert with an unknown expiry never does.
+//-----------------------------------------------------------------------------
+func (c SSLCert) ExpiresWithin(days int) bool {
+	if c.NotAfter.IsZero() {
+		return false
+	}
+	return time.Until(c.NotAfter) < time.Duration(days)*24*time.Hour
+}
+
+// parseCerts decodes all the CERTIFICATE blocks in a PEM file. Anything else in
+// the file, or no certificates at all, is an error.
+//-----------------------------------------------------------------------------
+func parseCerts(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := data
+	for {
+		var b *pem.Block
+		b, rest = pem.Decode(rest)
+		if b == nil {
+			break
+		}
+		if b.Type != "CERTIFICATE" {
+			return nil, errors.New("Unexpected '" + b.Type + "' block in certificate PEM")
+		}
+		c, err := x509.ParseCertificate(b.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("No certificates found in PEM")
+	}
+	if strings.TrimSpace(string(rest)) != "" {
+		return nil, errors.New("Trailing data after the certificates in PEM")
+	}
+	return certs, nil
+}
+
+// ParseCertPEM checks a PEM certificate (the first one, if there are several)
+// and returns its details. An expired certificate is an error.
+//-----------------------------------------------------------------------------
+func ParseCertPEM(data []byte) (SSLCert, error) {
+	certs, err := parseCerts(data)
+	if err != nil {
+		return SSLCert{}, err
+	}
+	c := certs[0]
+	sc := SSLCert{Type: "certificate", CommonName: c.Subject.CommonName, NotAfter: c.NotAfter}
+	if time.Now().After(c.NotAfter) {
+		return sc, errors.New("Certificate '" + c.Subject.CommonName + "' expired on " + c.NotAfter.Format(time.RFC3339))
+	}
+	return sc, nil
+}
+
+// CheckCertKey checks that a PEM certificate & key go together, and that the
+// (optional) chain passes CheckCertChain(). Returns the certificate details.
+//-----------------------------------------------------------------------------
+func CheckCertKey(cert []byte, key []byte, chain []byte) (SSLCert, error) {
+	sc, err := ParseCertPEM(cert)
+	if err != nil {
+		return sc, err
+	}
+	if _, err = tls.X509KeyPair(cert, key); err != nil {
+		return sc, err
+	}
+	if len(chain) > 0 {
+		if _, err = CheckCertChain(chain); err != nil {
+			return sc, err
+		}
+	}
+	return sc, nil
+}
+
+// thunderTime parses the expiry dates the Thunder lists certs with
+//-----------------------------------------------------------------------------
+func thunderTime(s string) time.Time {
+	for _, f := range []string{"Jan _2 15:04:05 2006 MST", "Jan _2 15:04:05 2006", time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(f, strings.TrimSpace(s)); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// GetSSLCerts()
+//-----------------------------------------------------------------------------
+func (d Device) GetSSLCerts() ([]SSLCert, error) {
+	return d.GetSSLCertsContext(context.Background())
+}
+
+// GetSSLCertsContext -- GetSSLCerts() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetSSLCertsContext(ctx context.Context) ([]SSLCert, error) {
+	var cl []SSLCert
+	body, err := d.sendJSON(ctx, "GET", "/slb/ssl-cert/oper", nil)
+	if err != nil {
+		return cl, err
+	}
+	for _, v := range gjson.GetBytes(body, "ssl-cert.oper.ssl-certs").Array() {
+		cl = append(cl, SSLCert{
+			Name:       v.Get("name").Str,
+			Type:       v.Get("type").Str,
+			CommonName: v.Get("common-name").Str,
+			NotAfter:   thunderTime(v.Get("notafter").Str),
+		})
+	}
+	return cl, nil
+}
+
+// GetExpiringSSLCerts()
+//-----------------------------------------------------------------------------
+// Lists the certificates on the Thunder that expire in the next 'days' days, or
+// already have.
+func (d Device) GetExpiringSSLCerts(days int) ([]SSLCert, error) {
+	return d.GetExpiringSSLCertsContext(context.Background(), days)
+}
+
+// GetExpiringSSLCertsContext -- GetExpiringSSLCerts() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetExpiringSSLCertsContext(ctx context.Context, days int) ([]SSLCert, error) {
+	cl, err := d.GetSSLCertsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ex []SSLCert
+	for _, c := range cl {
+		if c.ExpiresWithin(days) {
+			ex = append(ex, c)
+		}
+	}
+	return ex, nil
+}
+
+// UploadSSLCert()
+//-----------------------------------------------------------------------------
+// Imports a PEM certificate, overwriting any of the same name. The PEM is checked
+// before it is sent.
+func (d Device) UploadSSLCert(name string, cert []byte) error {
+	return d.UploadSSLCertContext(context.Background(), name, cert)
+}
+
+// UploadSSLCertContext -- UploadSSLCert() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UploadSSLCertContext(ctx context.Context, name string, cert []byte) error {
+	if _, err := ParseCertPEM(cert); err != nil {
+		return err
+	}
+	js := map[string]interface{}{"ssl-cert": map[string]string{"file": name, "file-handle": name + ".pem", "certificate-type": "pem", "action": "import"}}
+	_, err := d.sendFile(ctx, "/file/ssl-cert", js, name+".pem", cert)
+	return err
+}
+
+// UploadSSLChain()
+//-----------------------------------------------------------------------------
+// Imports a PEM file of intermediate/CA certificates, IE> to be used as the
+// 'chain-cert' of a client-ssl template.
+func (d Device) UploadSSLChain(name string, chain []byte) error {
+	return d.UploadSSLChainContext(context.Background(), name, chain)
+}
+
+// UploadSSLChainContext -- UploadSSLChain() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UploadSSLChainContext(ctx context.Context, name string, chain []byte) error {
+	if _, err := CheckCertChain(chain); err != nil {
+		return err
+	}
+	js := map[string]interface{}{"ssl-cert": map[string]string{"file": name, "file-handle": name + ".pem", "certificate-type": "pem", "action": "import"}}
+	_, err := d.sendFile(ctx, "/file/ssl-cert", js, name+".pem", chain)
+	return err
+}
+
+// CheckCertChain checks that every certificate in a PEM chain is a CA that
+// hasn't expired, and returns how many there are.
+//-----------------------------------------------------------------------------
+func CheckCertChain(chain []byte) (int, error) {
+	cc, err := parseCerts(chain)
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range cc {
+		if !c.IsCA {
+			return 0, errors.New("Chain certificate '" + c.Subject.CommonName + "' is not a CA")
+		}
+		if time.Now().After(c.NotAfter) {
+			return 0, errors.New("Chain certificate '" + c.Subject.CommonName + "' expired on " + c.NotAfter.Format(time.RFC3339))
+		}
+	}
+	return len(cc), nil
+}
+
+// UploadSSLKey()
+//-----------------------------------------------------------------------------
+// Imports a PEM private key, overwriting any of the same name. Use CheckCertKey()
+// first to make sure it goes with its certificate.
+func (d Device) UploadSSLKey(name string, key []byte) error {
+	return d.UploadSSLKeyContext(context.Background(), name, key)
+}
+
+// UploadSSLKeyContext -- UploadSSLKey() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) UploadSSLKeyContext(ctx context.Context, name string, key []byte) error {
+	b, _ := pem.Decode(key)
+	if b == nil || !strings.HasSuffix(b.Type, "PRIVATE KEY") {
+		return errors.New("No private key found in PEM")
+	}
+	js := map[string]interface{}{"ssl-key": map[string]string{"file": name, "file-handle": name + ".pem", "action": "import"}}
+	_, err := d.sendFile(ctx, "/file/ssl-key", js, name+".pem", key)
+	return err
+}
+
+// DeleteSSLCert()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteSSLCert(name string) error {
+	return d.DeleteSSLCertContext(context.Background(), name)
+}
+
+// DeleteSSLCertContext -- DeleteSSLCert() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteSSLCertContext(ctx context.Context, name string) error {
+	_, err := d.sendJSON(ctx, "POST", "/delete/ssl-cert", map[string]interface{}{"ssl-cert": map[string]string{"cert-name": name}})
+	return err
+}
+
+// DeleteSSLKey()
+//-----------------------------------------------------------------------------
+func (d Device) DeleteSSLKey(name string) error {
+	return d.DeleteSSLKeyContext(context.Background(), name)
+}
+
+// DeleteSSLKeyContext -- DeleteSSLKey() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) DeleteSSLKeyContext(ctx context.Context, name string) error {
+	_, err := d.sendJSON(ctx, "POST", "/delete/ssl-key", map[string]interface{}{"ssl-key": map[string]string{"key-name": name}})
+	return err
+}
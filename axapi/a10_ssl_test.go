@@ -0,0 +1,106 @@
+//
+//  a10_ssl.go tests
+//
+
+package axapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// testCert makes a self-signed PEM cert & key for 'cn' that expires at 'na'
+func testCert(t *testing.T, cn string, na time.Time, ca bool) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	notErr(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             na.Add(-365 * 24 * time.Hour),
+		NotAfter:              na,
+		IsCA:                  ca,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	notErr(t, err)
+	kd, err := x509.MarshalPKCS8PrivateKey(key)
+	notErr(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: kd})
+}
+
+func TestCheckCertKey(t *testing.T) {
+	na := time.Now().Add(30 * 24 * time.Hour)
+	cert, key := testCert(t, "www.example.com", na, false)
+	ca, _ := testCert(t, "Example CA", na, true)
+
+	sc, err := CheckCertKey(cert, key, ca)
+	notErr(t, err)
+	assert(t, sc.CommonName, "www.example.com")
+	assert(t, sc.ExpiresWithin(31), true)
+	assert(t, sc.ExpiresWithin(29), false)
+
+	// -- Key that doesn't go with the cert
+	_, other := testCert(t, "other", na, false)
+	_, err = CheckCertKey(cert, other, nil)
+	isErr(t, err, "mismatched key was accepted")
+
+	// -- Chain with a non-CA cert
+	_, err = CheckCertKey(cert, key, cert)
+	isErr(t, err, "non-CA chain was accepted")
+
+	// -- Expired cert
+	old, _ := testCert(t, "old", time.Now().Add(-time.Hour), false)
+	_, err = ParseCertPEM(old)
+	isErr(t, err, "expired cert was accepted")
+
+	// -- Not a cert at all
+	_, err = ParseCertPEM(key)
+	isErr(t, err, "key was accepted as a cert")
+	_, err = ParseCertPEM([]byte("junk"))
+	isErr(t, err, "junk was accepted as a cert")
+
+	n, err := CheckCertChain(append(ca, ca...))
+	notErr(t, err)
+	assert(t, n, 2)
+}
+
+func TestThunderTime(t *testing.T) {
+	assert(t, thunderTime("Jan  1 00:00:00 2030 GMT").Year(), 2030)
+	assert(t, thunderTime("2030-06-01").Month(), time.June)
+	assert(t, thunderTime("never").IsZero(), true)
+	assert(t, SSLCert{}.ExpiresWithin(10000), false)
+}
+
+func TestSSLCalls(t *testing.T) {
+	d := setup()
+	name := "axapi-test-cert"
+	cert, key := testCert(t, "axapi-test.example.com", time.Now().Add(10*24*time.Hour), false)
+	err := d.UploadSSLKey(name, key)
+	notErr(t, err)
+	err = d.UploadSSLCert(name, cert)
+	notErr(t, err)
+
+	ex, err := d.GetExpiringSSLCerts(30)
+	notErr(t, err)
+	found := false
+	for _, c := range ex {
+		found = found || c.Name == name
+	}
+	assert(t, found, true)
+
+	err = d.DeleteSSLCert(name)
+	notErr(t, err)
+	err = d.DeleteSSLKey(name)
+	notErr(t, err)
+
+	err = d.UploadSSLKey(name, cert)
+	isErr(t, err, "cert was accepted as a key")
+}
@@ -0,0 +1,425 @@
+//
+//  a10_stats.go  --  SLB Statistics & Oper State related aXAPI API calls
+//
+//  John D. Allen
+//  Sr. Solutions Engineer
+//  A10 Networks, Inc.
+//
+//  Copyright A10 Networks (c) 2020, All Rights Reserved.
+//
+
+package axapi
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Stats holds the traffic counters & health of an SLB object at one point in time.
+// The Total & Bytes counters only ever go up (until cleared), so rates come from
+// the difference between two samples -- see RatesSince() & Sampler.
+type Stats struct {
+	CurrConns  uint64
+	TotalConns uint64
+	CurrReqs   uint64
+	TotalReqs  uint64
+	BytesIn    uint64 // client -> server
+	BytesOut   uint64 // server -> client
+	Health     string // oper state, IE> "Up", "Down", "Functional Up"
+	Time       time.Time
+}
+
+// MemberStats holds the Stats for one member of a service-group
+type MemberStats struct {
+	Name string
+	Port int
+	Stats
+}
+
+// SvcGrpStats holds the Stats for a service-group, and each of its members. The
+// service-group's counters are the sum of its members'.
+type SvcGrpStats struct {
+	Name string
+	Stats
+	Members []MemberStats
+}
+
+// Rates holds the per-second rates between two Stats samples, along with the
+// gauges from the later one.
+type Rates struct {
+	ConnsPerSec    float64
+	ReqsPerSec     float64
+	BytesInPerSec  float64
+	BytesOutPerSec float64
+	CurrConns      uint64
+	CurrReqs       uint64
+	Health         string
+	Interval       time.Duration // time between the two samples
+}
+
+// statUint -- ACOS isn't consistent about '-' vs '_' in stats field names, so
+// take the first of the names that is there.
+//-----------------------------------------------------------------------------
+func statUint(r gjson.Result, names ...string) uint64 {
+	for _, n := range names {
+		if v := r.Get(n); v.Exists() {
+			return v.Uint()
+		}
+	}
+	return 0
+}
+
+// parseStats pulls the counters out of a 'stats' JSON object
+//-----------------------------------------------------------------------------
+func parseStats(r gjson.Result) Stats {
+	return Stats{
+		CurrConns:  statUint(r, "curr-conn", "curr_conn"),
+		TotalConns: statUint(r, "total-conn", "total_conn"),
+		CurrReqs:   statUint(r, "curr_req", "curr-req", "curr_request"),
+		TotalReqs:  statUint(r, "total_req", "total-req"),
+		BytesIn:    statUint(r, "total_fwd_bytes", "total-fwd-bytes"),
+		BytesOut:   statUint(r, "total_rev_bytes", "total-rev-bytes"),
+	}
+}
+
+// add sums the counters of two Stats
+//-----------------------------------------------------------------------------
+func (s Stats) add(o Stats) Stats {
+	s.CurrConns += o.CurrConns
+	s.TotalConns += o.TotalConns
+	s.CurrReqs += o.CurrReqs
+	s.TotalReqs += o.TotalReqs
+	s.BytesIn += o.BytesIn
+	s.BytesOut += o.BytesOut
+	return s
+}
+
+// getStats reads the '/stats' & '/oper' of an aXAPI object, IE> path of
+// "/slb/server/web1" with key "server"
+//-----------------------------------------------------------------------------
+func (d Device) getStats(ctx context.Context, path string, key string) (Stats, error) {
+	body, err := d.sendJSON(ctx, "GET", path+"/stats", nil)
+	if err != nil {
+		return Stats{}, err
+	}
+	st := parseStats(gjson.GetBytes(body, key+".stats"))
+	st.Time = time.Now()
+
+	body, err = d.sendJSON(ctx, "GET", path+"/oper", nil)
+	if err != nil {
+		return Stats{}, err
+	}
+	st.Health = gjson.GetBytes(body, key+".oper.state").Str
+	return st, nil
+}
+
+// GetServerStats()
+//-----------------------------------------------------------------------------
+func (d Device) GetServerStats(name string) (Stats, error) {
+	return d.GetServerStatsContext(context.Background(), name)
+}
+
+// GetServerStatsContext -- GetServerStats() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetServerStatsContext(ctx context.Context, name string) (Stats, error) {
+	return d.getStats(ctx, "/slb/server/"+url.PathEscape(name), "server")
+}
+
+// GetServerPortStats()
+//-----------------------------------------------------------------------------
+func (d Device) GetServerPortStats(name string, port int, proto string) (Stats, error) {
+	return d.GetServerPortStatsContext(context.Background(), name, port, proto)
+}
+
+// GetServerPortStatsContext -- GetServerPortStats() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetServerPortStatsContext(ctx context.Context, name string, port int, proto string) (Stats, error) {
+	return d.getStats(ctx, "/slb/server/"+url.PathEscape(name)+"/port/"+strconv.Itoa(port)+"+"+proto, "port")
+}
+
+// GetMemberStats()
+//-----------------------------------------------------------------------------
+func (d Device) GetMemberStats(sg string, name string, port int) (Stats, error) {
+	return d.GetMemberStatsContext(context.Background(), sg, name, port)
+}
+
+// GetMemberStatsContext -- GetMemberStats() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetMemberStatsContext(ctx context.Context, sg string, name string, port int) (Stats, error) {
+	return d.getStats(ctx, memberURL(sg, name, port), "member")
+}
+
+// GetServiceGroupStats()
+//-----------------------------------------------------------------------------
+// Gets the Stats of every member of the service-group in two API calls.
+func (d Device) GetServiceGroupStats(name string) (SvcGrpStats, error) {
+	return d.GetServiceGroupStatsContext(context.Background(), name)
+}
+
+// GetServiceGroupStatsContext -- GetServiceGroupStats() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetServiceGroupStatsContext(ctx context.Context, name string) (SvcGrpStats, error) {
+	sgs := SvcGrpStats{Name: name}
+	path := "/slb/service-group/" + url.PathEscape(name)
+	body, err := d.sendJSON(ctx, "GET", path+"/stats", nil)
+	if err != nil {
+		return sgs, err
+	}
+	now := time.Now()
+	sgs.Time = now
+	for _, m := range gjson.GetBytes(body, "service-group.member-list").Array() {
+		ms := MemberStats{Name: m.Get("name").Str, Port: int(m.Get("port").Int()), Stats: parseStats(m.Get("stats"))}
+		ms.Time = now
+		sgs.Stats = sgs.Stats.add(ms.Stats)
+		sgs.Members = append(sgs.Members, ms)
+	}
+
+	body, err = d.sendJSON(ctx, "GET", path+"/oper", nil)
+	if err != nil {
+		return sgs, err
+	}
+	sgs.Health = gjson.GetBytes(body, "service-group.oper.state").Str
+	for _, m := range gjson.GetBytes(body, "service-group.member-list").Array() {
+		for i := range sgs.Members {
+			if sgs.Members[i].Name == m.Get("name").Str && sgs.Members[i].Port == int(m.Get("port").Int()) {
+				sgs.Members[i].Health = m.Get("oper.state").Str
+			}
+		}
+	}
+	return sgs, nil
+}
+
+// RatesSince works out the per-second rates from an earlier sample of the same
+// object. A counter that went backwards (cleared, or the Thunder rebooted) gives
+// a rate of 0 rather than a huge number.
+//-----------------------------------------------------------------------------
+func (s Stats) RatesSince(prev Stats) Rates {
+	r := Rates{CurrConns: s.CurrConns, CurrReqs: s.CurrReqs, Health: s.Health, Interval: s.Time.Sub(prev.Time)}
+	secs := r.Interval.Seconds()
+	if secs <= 0 {
+		return r
+	}
+	rate := func(now uint64, then uint64) float64 {
+		if now < then {
+			return 0
+		}
+		return float64(now-then) / secs
+	}
+	r.ConnsPerSec = rate(s.TotalConns, prev.TotalConns)
+	r.ReqsPerSec = rate(s.TotalReqs, prev.TotalReqs)
+	r.BytesInPerSec = rate(s.BytesIn, prev.BytesIn)
+	r.BytesOutPerSec = rate(s.BytesOut, prev.BytesOut)
+	return r
+}
+
+// Sampler polls a Stats call every Interval, and hands the Rates since the last
+// sample to OnRates. Fetch is usually a closure around one of the Get*Stats
+// calls, IE>
+//
+//   s := Sampler{Interval: 10 * time.Second,
+//       Fetch:   func(ctx context.Context) (Stats, error) { return d.GetServerStatsContext(ctx, "web1") },
+//       OnRates: func(r Rates) { fmt.Printf("%.1f conn/s\n", r.ConnsPerSec) }}
+//   go s.Run(ctx)
+type Sampler struct {
+	Interval time.Duration
+	Fetch    func(ctx context.Context) (Stats, error)
+	OnRates  func(r Rates)
+	OnError  func(err error) // optional
+}
+
+// Run polls until the context is done, and returns its error. A failed Fetch is
+// passed to OnError, and the next good sample is compared to the last good one.
+// An Interval of zero or less is an error.
+//-----------------------------------------------------------------------------
+func (s Sampler) Run(ctx context.Context) error {
+	if s.Interval <= 0 {
+		return errors.New("Sampler Interval must be more than zero, got " + s.Interval.String())
+	}
+	var prev Stats
+	have := false
+	t := time.NewTicker(s.Interval)
+	defer t.Stop()
+	for {
+		st, err := s.Fetch(ctx)
+		if err != nil {
+			if s.OnError != nil && ctx.Err() == nil {
+				s.OnError(err)
+			}
+		} else {
+			if have {
+				s.OnRates(st.RatesSince(prev))
+			}
+			prev, have = st, true
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Normalized oper states
+const (
+	OperUp           = "up"
+	OperFunctionalUp = "functional-up" // up, but with some ports/members down
+	OperDown         = "down"
+	OperDisabled     = "disabled"
+	OperUnknown      = "unknown"
+)
+
+// OperState is the operational state of an SLB object
+type OperState struct {
+	State  string // one of the Oper* constants
+	Raw    string // the state as the Thunder reported it, IE> "All Up"
+	Reason string // why it isn't up, if the Thunder said
+}
+
+// PortOper holds the OperState of a virtual-server port
+type PortOper struct {
+	PortNumber int
+	Protocol   string
+	OperState
+}
+
+// VSOper holds the OperState of a virtual-server, and each of its ports
+type VSOper struct {
+	Name string
+	OperState
+	Ports []PortOper
+}
+
+// MemberOper holds the OperState of a service-group member
+type MemberOper struct {
+	Name string
+	Port int
+	OperState
+}
+
+// SvcGrpOper holds the OperState of a service-group, and each of its members
+type SvcGrpOper struct {
+	Name string
+	OperState
+	Members []MemberOper
+}
+
+// IsUp -- Is the object passing traffic? "functional-up" counts as up.
+//-----------------------------------------------------------------------------
+func (o OperState) IsUp() bool {
+	return o.State == OperUp || o.State == OperFunctionalUp
+}
+
+// parseOper pulls an OperState out of an 'oper' JSON object. ACOS uses different
+// words (and case) for the same state on different objects.
+//-----------------------------------------------------------------------------
+func parseOper(r gjson.Result) OperState {
+	o := OperState{Raw: r.Get("state").Str, State: OperUnknown}
+	switch strings.ToLower(o.Raw) {
+	case "up", "all up":
+		o.State = OperUp
+	case "functional up", "partial up":
+		o.State = OperFunctionalUp
+	case "down":
+		o.State = OperDown
+	case "disabled", "disb":
+		o.State = OperDisabled
+	}
+	for _, n := range []string{"down-reason", "down_reason", "reason", "state-reason"} {
+		if v := r.Get(n); v.Exists() {
+			o.Reason = v.String()
+			break
+		}
+	}
+	return o
+}
+
+// GetVirtualServerOper()
+//-----------------------------------------------------------------------------
+// Gets the OperState of a virtual-server and all of its ports in one API call.
+func (d Device) GetVirtualServerOper(name string) (VSOper, error) {
+	return d.GetVirtualServerOperContext(context.Background(), name)
+}
+
+// GetVirtualServerOperContext -- GetVirtualServerOper() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetVirtualServerOperContext(ctx context.Context, name string) (VSOper, error) {
+	vo := VSOper{Name: name}
+	body, err := d.sendJSON(ctx, "GET", "/slb/virtual-server/"+url.PathEscape(name)+"/oper", nil)
+	if err != nil {
+		return vo, err
+	}
+	vo.OperState = parseOper(gjson.GetBytes(body, "virtual-server.oper"))
+	for _, p := range gjson.GetBytes(body, "virtual-server.port-list").Array() {
+		vo.Ports = append(vo.Ports, PortOper{
+			PortNumber: int(p.Get("port-number").Int()),
+			Protocol:   p.Get("protocol").Str,
+			OperState:  parseOper(p.Get("oper")),
+		})
+	}
+	return vo, nil
+}
+
+// GetVirtualPortOper()
+//-----------------------------------------------------------------------------
+func (d Device) GetVirtualPortOper(vs string, port int, proto string) (OperState, error) {
+	return d.GetVirtualPortOperContext(context.Background(), vs, port, proto)
+}
+
+// GetVirtualPortOperContext -- GetVirtualPortOper() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetVirtualPortOperContext(ctx context.Context, vs string, port int, proto string) (OperState, error) {
+	body, err := d.sendJSON(ctx, "GET", vportURL(vs, port, proto)+"/oper", nil)
+	if err != nil {
+		return OperState{}, err
+	}
+	return parseOper(gjson.GetBytes(body, "port.oper")), nil
+}
+
+// GetServiceGroupOper()
+//-----------------------------------------------------------------------------
+// Gets the OperState of a service-group and all of its members in one API call.
+func (d Device) GetServiceGroupOper(name string) (SvcGrpOper, error) {
+	return d.GetServiceGroupOperContext(context.Background(), name)
+}
+
+// GetServiceGroupOperContext -- GetServiceGroupOper() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetServiceGroupOperContext(ctx context.Context, name string) (SvcGrpOper, error) {
+	so := SvcGrpOper{Name: name}
+	body, err := d.sendJSON(ctx, "GET", "/slb/service-group/"+url.PathEscape(name)+"/oper", nil)
+	if err != nil {
+		return so, err
+	}
+	so.OperState = parseOper(gjson.GetBytes(body, "service-group.oper"))
+	for _, m := range gjson.GetBytes(body, "service-group.member-list").Array() {
+		so.Members = append(so.Members, MemberOper{
+			Name:      m.Get("name").Str,
+			Port:      int(m.Get("port").Int()),
+			OperState: parseOper(m.Get("oper")),
+		})
+	}
+	return so, nil
+}
+
+// GetMemberOper()
+//-----------------------------------------------------------------------------
+func (d Device) GetMemberOper(sg string, name string, port int) (OperState, error) {
+	return d.GetMemberOperContext(context.Background(), sg, name, port)
+}
+
+// GetMemberOperContext -- GetMemberOper() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetMemberOperContext(ctx context.Context, sg string, name string, port int) (OperState, error) {
+	body, err := d.sendJSON(ctx, "GET", memberURL(sg, name, port)+"/oper", nil)
+	if err != nil {
+		return OperState{}, err
+	}
+	return parseOper(gjson.GetBytes(body, "member.oper")), nil
+}
@@ -0,0 +1,99 @@
+//
+//  a10_stats.go tests
+//
+
+package axapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestRatesSince(t *testing.T) {
+	t0 := time.Now()
+	a := Stats{TotalConns: 100, TotalReqs: 1000, BytesIn: 5000, BytesOut: 9000, Time: t0}
+	b := Stats{CurrConns: 7, TotalConns: 150, TotalReqs: 1500, BytesIn: 4000, BytesOut: 19000, Health: "Up", Time: t0.Add(10 * time.Second)}
+	r := b.RatesSince(a)
+	assert(t, r.ConnsPerSec, 5.0)
+	assert(t, r.ReqsPerSec, 50.0)
+	assert(t, r.BytesInPerSec, 0.0) // went backwards
+	assert(t, r.BytesOutPerSec, 1000.0)
+	assert(t, r.CurrConns, uint64(7))
+	assert(t, r.Health, "Up")
+}
+
+func TestServerStats(t *testing.T) {
+	d := setup()
+	sl, err := d.GetSLBservers()
+	notErr(t, err)
+	if len(sl) == 0 {
+		t.Skip("No slb servers on the Thunder")
+	}
+	st, err := d.GetServerStats(sl[0].Name)
+	notErr(t, err)
+	assertNot(t, st.Health, "")
+}
+
+func TestServiceGroupStats(t *testing.T) {
+	d := setup()
+	sgl, err := d.GetServiceGroups()
+	notErr(t, err)
+	if len(sgl) == 0 {
+		t.Skip("No service-groups on the Thunder")
+	}
+	sgs, err := d.GetServiceGroupStats(sgl[0].Name)
+	notErr(t, err)
+	assert(t, len(sgs.Members), len(sgl[0].Members))
+}
+
+func TestSampler(t *testing.T) {
+	d := setup()
+	sl, err := d.GetSLBservers()
+	notErr(t, err)
+	if len(sl) == 0 {
+		t.Skip("No slb servers on the Thunder")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+	n := 0
+	s := Sampler{
+		Interval: time.Second,
+		Fetch:    func(ctx context.Context) (Stats, error) { return d.GetServerStatsContext(ctx, sl[0].Name) },
+		OnRates:  func(r Rates) { n++ },
+		OnError:  func(err error) { notErr(t, err) },
+	}
+	s.Run(ctx)
+	assert(t, n, 2)
+}
+
+func TestSamplerInterval(t *testing.T) {
+	s := Sampler{Fetch: func(ctx context.Context) (Stats, error) { return Stats{}, nil }, OnRates: func(r Rates) {}}
+	err := s.Run(context.Background()) // this SHOULD err, not panic
+	isErr(t, err, "Uncaught test for zero Sampler Interval")
+}
+
+func TestParseOper(t *testing.T) {
+	o := parseOper(gjson.Parse(`{"state": "Functional Up"}`))
+	assert(t, o.State, OperFunctionalUp)
+	assert(t, o.IsUp(), true)
+	o = parseOper(gjson.Parse(`{"state": "DOWN", "down-reason": "health check failed"}`))
+	assert(t, o.State, OperDown)
+	assert(t, o.Reason, "health check failed")
+	assert(t, o.IsUp(), false)
+}
+
+func TestVirtualServerOper(t *testing.T) {
+	d := setup()
+	vsl, err := d.GetVSlist()
+	notErr(t, err)
+	if len(vsl) == 0 {
+		t.Skip("No virtual-servers on the Thunder")
+	}
+	vo, err := d.GetVirtualServerOper(vsl[0].Name)
+	notErr(t, err)
+	assertNot(t, vo.State, "")
+	assert(t, len(vo.Ports), len(vsl[0].Ports))
+}
@@ -11,9 +11,10 @@
 package axapi
 
 import (
-	"fmt"
+	"context"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/tidwall/gjson"
 )
@@ -21,7 +22,13 @@ import (
 // GetUptime returns the Thunder's uptime as a string
 //-----------------------------------------------------------------------------
 func (d Device) GetUptime() (string, error) {
-	body, err := _restCall(d, "/version/oper", "GET", nil)
+	return d.GetUptimeContext(context.Background())
+}
+
+// GetUptimeContext -- GetUptime() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetUptimeContext(ctx context.Context) (string, error) {
+	body, err := _restCall(ctx, d, "/version/oper", "GET", nil)
 	if err != nil {
 		return "", err
 	}
@@ -36,7 +43,13 @@ func (d Device) GetUptime() (string, error) {
 // GetPlatform -- What hardware/software is Thunder running on?
 //-----------------------------------------------------------------------------
 func (d Device) GetPlatform() (string, error) {
-	body, err := _restCall(d, "/version/oper", "GET", nil)
+	return d.GetPlatformContext(context.Background())
+}
+
+// GetPlatformContext -- GetPlatform() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetPlatformContext(ctx context.Context) (string, error) {
+	body, err := _restCall(ctx, d, "/version/oper", "GET", nil)
 	if err != nil {
 		return "", err
 	}
@@ -58,8 +71,14 @@ type BootInfo struct {
 // GetBootInfo - Return struct with info about the two partitions, and which one is booting from.
 //-----------------------------------------------------------------------------
 func (d Device) GetBootInfo() (BootInfo, error) {
+	return d.GetBootInfoContext(context.Background())
+}
+
+// GetBootInfoContext -- GetBootInfo() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetBootInfoContext(ctx context.Context) (BootInfo, error) {
 	var b BootInfo
-	body, err := _restCall(d, "/bootimage/oper", "GET", nil)
+	body, err := _restCall(ctx, d, "/bootimage/oper", "GET", nil)
 	if err != nil {
 		return BootInfo{}, nil
 	}
@@ -77,7 +96,13 @@ func (d Device) GetBootInfo() (BootInfo, error) {
 // GetLastConfigSave - Returns string with time/date of last config save (IE> mem wr)
 //-----------------------------------------------------------------------------
 func (d Device) GetLastConfigSave() (string, error) {
-	body, err := _restCall(d, "/version/oper", "GET", nil)
+	return d.GetLastConfigSaveContext(context.Background())
+}
+
+// GetLastConfigSaveContext -- GetLastConfigSave() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetLastConfigSaveContext(ctx context.Context) (string, error) {
+	body, err := _restCall(ctx, d, "/version/oper", "GET", nil)
 	if err != nil {
 		return "", err
 	}
@@ -92,7 +117,13 @@ func (d Device) GetLastConfigSave() (string, error) {
 // GetControlCPUs - Returns number of control CPUs
 //-----------------------------------------------------------------------------
 func (d Device) GetControlCPUs() (int, error) {
-	body, err := _restCall(d, "/version/oper", "GET", nil)
+	return d.GetControlCPUsContext(context.Background())
+}
+
+// GetControlCPUsContext -- GetControlCPUs() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetControlCPUsContext(ctx context.Context) (int, error) {
+	body, err := _restCall(ctx, d, "/version/oper", "GET", nil)
 	if err != nil {
 		return 0, err
 	}
@@ -107,7 +138,13 @@ func (d Device) GetControlCPUs() (int, error) {
 // GetTimezone - What is the Timezone setting on the Thunder device.
 //-----------------------------------------------------------------------------
 func (d Device) GetTimezone() (string, error) {
-	body, err := _restCall(d, "/timezone/oper", "GET", nil)
+	return d.GetTimezoneContext(context.Background())
+}
+
+// GetTimezoneContext -- GetTimezone() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetTimezoneContext(ctx context.Context) (string, error) {
+	body, err := _restCall(ctx, d, "/timezone/oper", "GET", nil)
 	if err != nil {
 		return "", err
 	}
@@ -122,9 +159,15 @@ func (d Device) GetTimezone() (string, error) {
 // SetTimezone - Set the TZ string
 //-----------------------------------------------------------------------------
 func (d Device) SetTimezone(tz string) error {
+	return d.SetTimezoneContext(context.Background(), tz)
+}
+
+// SetTimezoneContext -- SetTimezone() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SetTimezoneContext(ctx context.Context, tz string) error {
 	payload := strings.NewReader("{ \"timezone\": {\"timezone-index-cfg\": {\"timezone-index\": \"" + tz + "\" } } }")
 
-	body, err := _restCall(d, "/timezone", "POST", payload)
+	body, err := _restCall(ctx, d, "/timezone", "POST", payload)
 	if err != nil {
 		return err
 	}
@@ -139,9 +182,15 @@ func (d Device) SetTimezone(tz string) error {
 // SetHostname - Set the Hostname for the Thunder device
 //-----------------------------------------------------------------------------
 func (d Device) SetHostname(hn string) error {
+	return d.SetHostnameContext(context.Background(), hn)
+}
+
+// SetHostnameContext -- SetHostname() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) SetHostnameContext(ctx context.Context, hn string) error {
 	payload := strings.NewReader("{ \"hostname\": {\"value\": \"" + hn + "\" } }")
 
-	body, err := _restCall(d, "/hostname", "PUT", payload)
+	body, err := _restCall(ctx, d, "/hostname", "PUT", payload)
 	if err != nil {
 		return err
 	}
@@ -156,8 +205,14 @@ func (d Device) SetHostname(hn string) error {
 // GetProcessInfo - Get the list of running processes
 //-----------------------------------------------------------------------------
 func (d Device) GetProcessInfo() ([]string, error) {
+	return d.GetProcessInfoContext(context.Background())
+}
+
+// GetProcessInfoContext -- GetProcessInfo() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetProcessInfoContext(ctx context.Context) ([]string, error) {
 	var rr []string
-	body, err := _restCall(d, "/system-view/show-process/oper", "GET", nil)
+	body, err := _restCall(ctx, d, "/system-view/show-process/oper", "GET", nil)
 	if err != nil {
 		return []string{}, err
 	}
@@ -180,20 +235,185 @@ func (d Device) GetProcessInfo() ([]string, error) {
 
 // CliDeploy - Run a CLI command via the API call
 //-----------------------------------------------------------------------------
+// Returns the raw CLI output, including the prompts & command echo. Use CliBatch()
+// to get the output of each command on its own.
 func (d Device) CliDeploy(cmd string) (string, error) {
+	return d.CliDeployContext(context.Background(), cmd)
+}
+
+// CliDeployContext -- CliDeploy() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CliDeployContext(ctx context.Context, cmd string) (string, error) {
 	c := strings.NewReader(cmd)
-	body, err := _restCall(d, "/clideploy", "POST", c)
+	body, err := _restCall(ctx, d, "/clideploy", "POST", c)
 	if err != nil {
 		return "", err
 	}
 
-	if gjson.Valid(string(body)) {
-		if e, msg := d.chkResp(body); e {
-			return "", msg
-		}
-		fmt.Println(">>" + string(body))
-		panic(string(body))
-	} else {
+	if !gjson.ValidBytes(body) {
 		return string(body), nil
 	}
+	//
+	// A JSON response is either a failure, or an OK with no output
+	if e, msg := d.chkResp(body); e {
+		return "", msg
+	}
+	if gjson.GetBytes(body, "response.status").Str == "OK" {
+		return "", nil
+	}
+	return "", &CliError{Cmd: strings.TrimSpace(cmd), Message: "unexpected JSON response: " + string(body)}
+}
+
+// CliError is returned for a CLI command that printed an error, or whose output
+// couldn't be made sense of.
+type CliError struct {
+	Cmd     string
+	Message string
+}
+
+// Error -- the error interface
+//-----------------------------------------------------------------------------
+func (e *CliError) Error() string {
+	return "CLI '" + e.Cmd + "': " + e.Message
+}
+
+// CliResult is the outcome of one command of a CliBatch()
+type CliResult struct {
+	Cmd    string
+	Output string // what the command printed, without the prompt & echo
+	Err    error  // a *CliError if the command printed an error
+}
+
+// CliBatch - Run a list of CLI commands in one CLI session
+//-----------------------------------------------------------------------------
+// The commands run in order, as if typed in (so "configure" followed by config
+// commands works). Every command is run even if an earlier one fails; the error
+// returned is the first failure, and each CliResult has its own. With writeMem,
+// a 'write memory' is run afterwards -- but only if all the commands worked --
+// and its result is added to the end of the list.
+func (d Device) CliBatch(cmds []string, writeMem bool) ([]CliResult, error) {
+	return d.CliBatchContext(context.Background(), cmds, writeMem)
+}
+
+// CliBatchContext -- CliBatch() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) CliBatchContext(ctx context.Context, cmds []string, writeMem bool) ([]CliResult, error) {
+	res, err := d.cliRun(ctx, cmds)
+	if err != nil || !writeMem {
+		return res, err
+	}
+	wr, err := d.cliRun(ctx, []string{"write memory"})
+	return append(res, wr...), err
+}
+
+// cliRun sends the commands in one CliDeploy() call, and splits up the output
+//-----------------------------------------------------------------------------
+func (d Device) cliRun(ctx context.Context, cmds []string) ([]CliResult, error) {
+	var res []CliResult
+	var sb strings.Builder
+	for _, c := range cmds {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		res = append(res, CliResult{Cmd: strings.TrimSpace(c)})
+		sb.WriteString(c + "\n")
+	}
+	if len(res) == 0 {
+		return res, nil
+	}
+	out, err := d.CliDeployContext(ctx, sb.String())
+	if err != nil {
+		for i := range res {
+			res[i].Err = err
+		}
+		return res, err
+	}
+	return res, splitCliOutput(out, res)
+}
+
+// splitCliOutput fills in the Output & Err of each result from the CLI output of the
+// whole batch, using the prompt & echo of each command to find where its output
+// starts. Returns the first error.
+//-----------------------------------------------------------------------------
+func splitCliOutput(out string, res []CliResult) error {
+	cur := -1
+	var lines [][]string
+	for _, l := range strings.Split(strings.Replace(out, "\r\n", "\n", -1), "\n") {
+		if cur+1 < len(res) && isEcho(l, res[cur+1].Cmd) {
+			cur++
+			lines = append(lines, nil)
+			continue
+		}
+		if cur >= 0 {
+			lines[cur] = append(lines[cur], l)
+		}
+	}
+
+	var first error
+	for i := range res {
+		if i > cur {
+			res[i].Err = &CliError{Cmd: res[i].Cmd, Message: "command not found in the CLI output"}
+		} else {
+			ll := lines[i]
+			for len(ll) > 0 {
+				t := strings.TrimSpace(ll[len(ll)-1])
+				if t != "" && !isPrompt(t) {
+					break
+				}
+				ll = ll[:len(ll)-1]
+			}
+			if len(ll) > 0 {
+				res[i].Output = strings.Join(ll, "\n") + "\n"
+			}
+			if e := cliError(res[i].Output); e != "" {
+				res[i].Err = &CliError{Cmd: res[i].Cmd, Message: e}
+			}
+		}
+		if first == nil {
+			first = res[i].Err
+		}
+	}
+	return first
+}
+
+// isEcho -- Is the line the prompt followed by the command? IE> "vThunder(config)#end"
+//-----------------------------------------------------------------------------
+func isEcho(l string, cmd string) bool {
+	t := strings.TrimSpace(l)
+	if !strings.HasSuffix(t, cmd) {
+		return false
+	}
+	p := strings.TrimSpace(t[:len(t)-len(cmd)])
+	return p != "" && isPrompt(p)
+}
+
+// isPrompt -- Is the line a bare CLI prompt? IE> "vThunder#" or "vThunder(config)#".
+// The prompt starts with the hostname, so a line like "#exit" in the output of
+// a command (IE> a comment in an aFleX script) isn't taken for one.
+//-----------------------------------------------------------------------------
+func isPrompt(l string) bool {
+	if !strings.HasSuffix(l, "#") && !strings.HasSuffix(l, ">") {
+		return false
+	}
+	l = l[:len(l)-1]
+	// -- The mode in brackets may have a space, IE> "vThunder(config-slb vserver)#"
+	if i := strings.Index(l, "("); i > 0 && strings.HasSuffix(l, ")") {
+		l = l[:i]
+	}
+	if l == "" || !unicode.IsLetter(rune(l[0])) && !unicode.IsDigit(rune(l[0])) {
+		return false
+	}
+	return !strings.ContainsAny(l, " \t#>")
+}
+
+// cliError returns the first error line in CLI output, or "" if there are none
+//-----------------------------------------------------------------------------
+func cliError(out string) string {
+	for _, l := range strings.Split(out, "\n") {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "Error") || strings.HasPrefix(l, "% ") {
+			return l
+		}
+	}
+	return ""
 }
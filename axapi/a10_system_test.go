@@ -6,6 +6,7 @@ package axapi
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -49,3 +50,72 @@ func TestGetProcesses(t *testing.T) {
 	arrContains(t, f, "a10mon")
 	arrContains(t, f, "a10logd")
 }
+
+func TestSplitCliOutput(t *testing.T) {
+	out := "vThunder#show running-config\r\n!Current configuration: 120 bytes\r\nhostname vThunder\r\nend\r\n" +
+		"vThunder#configure\r\nvThunder(config)#slb virtual-server ws-vip 10.1.1.1\r\n" +
+		"vThunder(config-slb vserver)#port 443 https\r\nError: Invalid port\r\nvThunder(config-slb vserver)#"
+	res := []CliResult{{Cmd: "show running-config"}, {Cmd: "configure"}, {Cmd: "slb virtual-server ws-vip 10.1.1.1"}, {Cmd: "port 443 https"}, {Cmd: "end"}}
+	err := splitCliOutput(out, res)
+	isErr(t, err, "CLI error not reported")
+	assert(t, strings.HasPrefix(res[0].Output, "!Current configuration"), true)
+	assert(t, strings.HasSuffix(res[0].Output, "end\n"), true)
+	assert(t, res[0].Err == nil, true)
+	assert(t, res[1].Output, "")
+	assert(t, res[3].Output, "Error: Invalid port\n")
+	assert(t, res[3].Err.Error(), "CLI 'port 443 https': Error: Invalid port")
+	// -- 'end' never showed up in the output
+	isErr(t, res[4].Err, "missing command not reported")
+
+	assert(t, isPrompt("vThunder(config-slb vserver)#"), true)
+	assert(t, isPrompt("! ip nat#"), false)
+	assert(t, isEcho("vThunder>show version", "show version"), true)
+	assert(t, isEcho("show version", "show version"), false)
+
+	// -- The same command more than once in a batch, as RestoreConfig() sends
+	out = "vThunder#configure\r\n" +
+		"vThunder(config)#slb server s1 10.0.0.1\r\nvThunder(config-real server)#port 80 tcp\r\n" +
+		"vThunder(config-real server-node port)#exit\r\nvThunder(config-real server)#exit\r\nvThunder(config)#!\r\n" +
+		"vThunder(config)#slb server s2 10.0.0.2\r\nError: Duplicate IP\r\nvThunder(config)#exit\r\n" +
+		"vThunder#!\r\nvThunder#"
+	res = []CliResult{{Cmd: "configure"}, {Cmd: "slb server s1 10.0.0.1"}, {Cmd: "port 80 tcp"}, {Cmd: "exit"}, {Cmd: "exit"},
+		{Cmd: "!"}, {Cmd: "slb server s2 10.0.0.2"}, {Cmd: "exit"}, {Cmd: "!"}}
+	err = splitCliOutput(out, res)
+	isErr(t, err, "CLI error not reported")
+	for i, r := range res {
+		if i == 6 {
+			assert(t, r.Output, "Error: Duplicate IP\n")
+			isErr(t, r.Err, "CLI error not reported")
+			continue
+		}
+		assert(t, r.Output, "")
+		notErr(t, r.Err)
+	}
+
+	// -- Output with '#' in it, IE> comments in an aFleX script
+	out = "vThunder#show aflex opa-v1\r\n" +
+		"#exit\r\n# set the pool\r\n(config)#exit\r\nwhen HTTP_REQUEST { pool #1 }\r\n#\r\n" +
+		"vThunder#exit\r\n"
+	res = []CliResult{{Cmd: "show aflex opa-v1"}, {Cmd: "exit"}}
+	err = splitCliOutput(out, res)
+	notErr(t, err)
+	assert(t, res[0].Output, "#exit\n# set the pool\n(config)#exit\nwhen HTTP_REQUEST { pool #1 }\n#\n")
+	assert(t, res[1].Output, "")
+	notErr(t, res[1].Err)
+	assert(t, isPrompt("#"), false)
+	assert(t, isPrompt("(config)#"), false)
+	assert(t, isPrompt("vThunder-Active-vMaster[1/1](config)#"), true)
+}
+
+func TestCliBatch(t *testing.T) {
+	d := setup()
+	res, err := d.CliBatch([]string{"show version", "", "show bogus-command"}, false)
+	isErr(t, err, "bad command not reported")
+	assert(t, len(res), 2)
+	notErr(t, res[0].Err)
+	isErr(t, res[1].Err, "bad command not reported")
+
+	res, err = d.CliBatch([]string{"show version"}, true)
+	notErr(t, err)
+	assert(t, res[len(res)-1].Cmd, "write memory")
+}
@@ -0,0 +1,145 @@
+//
+//  a10_vrrp.go  --  VRRP-A High Availability & Config-Sync related aXAPI API calls
+//
+//  John D. Allen
+//  Sr. Solutions Engineer
+//  A10 Networks, Inc.
+//
+//  Copyright A10 Networks (c) 2020, All Rights Reserved.
+//
+
+package axapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// VRRPState is the state of one VRRP-A VRID on this Thunder
+type VRRPState struct {
+	VRID     int
+	State    string // "Active", "Standby", ...
+	Priority int
+	Peer     string // the peer's state for the VRID, if known
+}
+
+// IsActive -- Is this Thunder the active unit for the VRID?
+//-----------------------------------------------------------------------------
+func (v VRRPState) IsActive() bool {
+	return strings.EqualFold(v.State, "Active")
+}
+
+// ConfigSync is the state of config-sync with the HA peer
+type ConfigSync struct {
+	Status   string // as reported, IE> "In Sync", "Out of Sync"
+	Peer     string
+	LastSync string
+}
+
+// InSync -- Is the peer's config the same as ours?
+//-----------------------------------------------------------------------------
+func (c ConfigSync) InSync() bool {
+	s := strings.ToLower(strings.Replace(c.Status, "-", " ", -1))
+	return strings.Contains(s, "in sync") || s == "synced"
+}
+
+// GetVRRPAState()
+//-----------------------------------------------------------------------------
+// Returns the state of each VRID. The list is empty when VRRP-A isn't set up.
+func (d Device) GetVRRPAState() ([]VRRPState, error) {
+	return d.GetVRRPAStateContext(context.Background())
+}
+
+// GetVRRPAStateContext -- GetVRRPAState() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetVRRPAStateContext(ctx context.Context) ([]VRRPState, error) {
+	var vl []VRRPState
+	body, err := d.sendJSON(ctx, "GET", "/vrrp-a/state/oper", nil)
+	if err != nil {
+		if IsNotFound(err) {
+			return vl, nil
+		}
+		return vl, err
+	}
+	for _, v := range gjson.GetBytes(body, "state.oper.vrid-list").Array() {
+		vl = append(vl, VRRPState{
+			VRID:     int(v.Get("vrid").Int()),
+			State:    v.Get("state").Str,
+			Priority: int(v.Get("priority").Int()),
+			Peer:     v.Get("peer-state").Str,
+		})
+	}
+	return vl, nil
+}
+
+// IsVRRPAActive()
+//-----------------------------------------------------------------------------
+// Is this Thunder the active unit for the VRID? A Thunder without VRRP-A (or
+// without that VRID) is standalone, and so always active.
+func (d Device) IsVRRPAActive(vrid int) (bool, error) {
+	return d.IsVRRPAActiveContext(context.Background(), vrid)
+}
+
+// IsVRRPAActiveContext -- IsVRRPAActive() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) IsVRRPAActiveContext(ctx context.Context, vrid int) (bool, error) {
+	vl, err := d.GetVRRPAStateContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range vl {
+		if v.VRID == vrid {
+			return v.IsActive(), nil
+		}
+	}
+	return true, nil
+}
+
+// GetVRIDState()
+//-----------------------------------------------------------------------------
+// Returns the state of a single VRID. Unlike IsVRRPAActive(), a VRID that isn't
+// set up (or VRRP-A not being set up at all) is a not found APIError, for use
+// when the Thunder is known to be one of a pair.
+func (d Device) GetVRIDState(vrid int) (VRRPState, error) {
+	return d.GetVRIDStateContext(context.Background(), vrid)
+}
+
+// GetVRIDStateContext -- GetVRIDState() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetVRIDStateContext(ctx context.Context, vrid int) (VRRPState, error) {
+	vl, err := d.GetVRRPAStateContext(ctx)
+	if err != nil {
+		return VRRPState{}, err
+	}
+	for _, v := range vl {
+		if v.VRID == vrid {
+			return v, nil
+		}
+	}
+	return VRRPState{}, &APIError{StatusCode: http.StatusNotFound, Message: "VRRP-A VRID " + strconv.Itoa(vrid) + " is not set up", Method: "GET", Path: "/vrrp-a/state/oper"}
+}
+
+// GetConfigSyncStatus()
+//-----------------------------------------------------------------------------
+func (d Device) GetConfigSyncStatus() (ConfigSync, error) {
+	return d.GetConfigSyncStatusContext(context.Background())
+}
+
+// GetConfigSyncStatusContext -- GetConfigSyncStatus() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetConfigSyncStatusContext(ctx context.Context) (ConfigSync, error) {
+	body, err := d.sendJSON(ctx, "GET", "/configure/sync/oper", nil)
+	if err != nil {
+		return ConfigSync{}, err
+	}
+	v := gjson.GetBytes(body, "sync.oper")
+	return ConfigSync{
+		Status:   v.Get("sync-status").Str,
+		Peer:     v.Get("peer").Str,
+		LastSync: v.Get("last-sync").Str,
+	}, nil
+}
@@ -0,0 +1,42 @@
+//
+//  a10_vrrp.go tests
+//
+
+package axapi
+
+import (
+	"testing"
+)
+
+func TestVRRPStateFlags(t *testing.T) {
+	assert(t, VRRPState{State: "Active"}.IsActive(), true)
+	assert(t, VRRPState{State: "Standby"}.IsActive(), false)
+	assert(t, ConfigSync{Status: "In Sync"}.InSync(), true)
+	assert(t, ConfigSync{Status: "in-sync"}.InSync(), true)
+	assert(t, ConfigSync{Status: "Out of Sync"}.InSync(), false)
+	assert(t, ConfigSync{}.InSync(), false)
+}
+
+func TestVRRPACalls(t *testing.T) {
+	d := setup()
+	vl, err := d.GetVRRPAState()
+	notErr(t, err)
+	act, err := d.IsVRRPAActive(0)
+	notErr(t, err)
+	if len(vl) == 0 {
+		// -- Standalone Thunder is always active
+		assert(t, act, true)
+	}
+	st, err := d.GetVRIDState(0)
+	if len(vl) == 0 {
+		// -- ...but has no VRID to report
+		assert(t, IsNotFound(err), true)
+	}
+	for _, v := range vl {
+		if v.VRID == 0 {
+			assert(t, act, v.IsActive())
+			notErr(t, err)
+			assert(t, st, v)
+		}
+	}
+}
@@ -12,12 +12,25 @@
 package axapi
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"crypto/tls"
-	//"encoding/json"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"io"
 	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/textproto"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/tidwall/gjson"
 )
@@ -29,68 +42,385 @@ type Device struct {
 	Username     string
 	Password     string
 	Address      string
-	Token        string
+	Token        string // as of Login(), or the renewal when passed to a hook -- see CurrentToken()
 	Version      string
 	Hardware     string
 	BootFrom     string
 	SerialNumber string
+	// HTTP holds the timeouts & connection pool settings for talking to the Thunder
+	HTTP ClientConfig
+	// TLS holds how the Thunder's certificate is verified
+	TLS TLSConfig
+	// Client, if set, is used as-is for all API calls instead of one built from HTTP
+	Client *http.Client
+	// Hooks are called on aXAPI session events, IE> for logging
+	Hooks SessionHooks
+	// Partition, if set, is made the active partition at Login(), and again
+	// whenever the session is renewed
+	Partition string
+	// session is shared by all copies of the Device made after Login()
+	session *session
 }
 
-// _restCall is the basic API callout function
+// SessionHooks are optional callbacks for the aXAPI session lifecycle. Any of them
+// may be left nil.
+type SessionHooks struct {
+	OnLogin       func(d Device)             // After a successful Login()
+	OnExpired     func(d Device, url string) // An API call was refused for a bad/expired token
+	OnRenew       func(d Device)             // After a successful automatic re-login
+	OnRenewFailed func(d Device, err error)  // The automatic re-login failed
+	OnLogoff      func(d Device)             // After a successful Logoff()
+	// After every HTTP request to the Thunder, IE> for metrics. Status is 0 if
+	// there was no response.
+	OnCall func(d Device, method string, url string, status int, took time.Duration)
+}
+
+// session holds the current auth token for a logged in Device. When the Thunder
+// refuses a token (session timeout, reboot, etc.) it is renewed by logging in
+// again with the Device's credentials.
+type session struct {
+	mu        sync.Mutex
+	token     string
+	partition string     // active partition of the token, "" for shared
+	part      sync.Mutex // held by WithPartition()
+}
+
+// ClientConfig holds the HTTP client settings for a Device. Any zero value
+// uses the matching default from DefaultClientConfig.
+type ClientConfig struct {
+	Timeout             time.Duration // Overall limit for an API call, including reading the body
+	DialTimeout         time.Duration // Limit on making the TCP connection
+	TLSHandshakeTimeout time.Duration // Limit on the TLS handshake
+	KeepAlive           time.Duration // TCP keep-alive period
+	IdleConnTimeout     time.Duration // How long an idle pooled connection is kept
+	MaxIdleConns        int           // Max idle pooled connections to the Thunder
+}
+
+// DefaultClientConfig holds the settings used for any ClientConfig values left unset
+var DefaultClientConfig = ClientConfig{
+	Timeout:             30 * time.Second,
+	DialTimeout:         10 * time.Second,
+	TLSHandshakeTimeout: 10 * time.Second,
+	KeepAlive:           30 * time.Second,
+	IdleConnTimeout:     90 * time.Second,
+	MaxIdleConns:        4,
+}
+
+// TLSConfig holds the TLS settings for a Device. With nothing set, the Thunder's
+// certificate must verify against the system CA roots.
+type TLSConfig struct {
+	CAFile      string // PEM bundle of CAs to verify the Thunder's certificate against
+	Fingerprint string // SHA-256 fingerprint of the Thunder's certificate (hex, ':'s optional)
+	ServerName  string // Name to verify the certificate for, if not the Address
+	CertFile    string // PEM client certificate to present to the Thunder
+	KeyFile     string // PEM key for CertFile
+	Insecure    bool   // Skip ALL certificate checks -- logs a warning when used. Can't be used with Fingerprint.
+}
+
+// clientKey is what a long-lived http.Client is cached under
+type clientKey struct {
+	HTTP ClientConfig
+	TLS  TLSConfig
+}
+
+// clients holds one long-lived http.Client per distinct clientKey, so that all
+// the copies of a Device share the same connection pool.
+var clients sync.Map
+
+// withDefaults fills in any unset ClientConfig values
 //-----------------------------------------------------------------------------
-func _restCall(d Device, url string, method string, payload *strings.Reader) ([]byte, error) {
-	var body []byte
-	if d.Token == "" && url != "/auth" {
-		return []byte{}, errors.New("No A10 Auth Token! You must Login() before calling other API calls")
+func (c ClientConfig) withDefaults() ClientConfig {
+	if c.Timeout == 0 {
+		c.Timeout = DefaultClientConfig.Timeout
+	}
+	if c.DialTimeout == 0 {
+		c.DialTimeout = DefaultClientConfig.DialTimeout
+	}
+	if c.TLSHandshakeTimeout == 0 {
+		c.TLSHandshakeTimeout = DefaultClientConfig.TLSHandshakeTimeout
+	}
+	if c.KeepAlive == 0 {
+		c.KeepAlive = DefaultClientConfig.KeepAlive
 	}
+	if c.IdleConnTimeout == 0 {
+		c.IdleConnTimeout = DefaultClientConfig.IdleConnTimeout
+	}
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = DefaultClientConfig.MaxIdleConns
+	}
+	return c
+}
 
-	u := "https://" + d.Address + "/axapi/v3" + url
-	if method == "" {
-		method = "GET"
+// tlsConfig builds the crypto/tls config from a TLSConfig
+//-----------------------------------------------------------------------------
+func (t TLSConfig) tlsConfig() (*tls.Config, error) {
+	if t.Insecure && t.Fingerprint != "" {
+		return nil, errors.New("TLSConfig.Insecure would skip the Fingerprint check; set only Fingerprint to pin a self-signed Thunder certificate")
+	}
+	if t.Insecure {
+		log.Println("WARNING: axapi: Thunder certificate verification is disabled (TLSConfig.Insecure)")
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+
+	tc := &tls.Config{ServerName: t.ServerName}
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("No certificates found in CA file " + t.CAFile)
+		}
+		tc.RootCAs = pool
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	if t.Fingerprint != "" {
+		want, err := hex.DecodeString(strings.ReplaceAll(t.Fingerprint, ":", ""))
+		if err != nil || len(want) != sha256.Size {
+			return nil, errors.New("Invalid SHA-256 certificate fingerprint: " + t.Fingerprint)
+		}
+		// With no CA bundle, a pinned cert is all we check (IE> self-signed Thunders).
+		// With one, the cert must verify against the CAs AND match the pin.
+		if t.CAFile == "" {
+			tc.InsecureSkipVerify = true
+		}
+		tc.VerifyPeerCertificate = func(raw [][]byte, _ [][]*x509.Certificate) error {
+			if len(raw) == 0 {
+				return errors.New("Thunder presented no certificate")
+			}
+			got := sha256.Sum256(raw[0])
+			if subtle.ConstantTimeCompare(got[:], want) != 1 {
+				return errors.New("Thunder certificate fingerprint mismatch: got " + hex.EncodeToString(got[:]))
+			}
+			return nil
+		}
+	}
+	return tc, nil
+}
+
+// certHint logs how to fix a Thunder certificate that doesn't verify, IE> the
+// self-signed one a Thunder ships with.
+//-----------------------------------------------------------------------------
+func certHint(d Device, err error) {
+	var ua x509.UnknownAuthorityError
+	var he x509.HostnameError
+	var ci x509.CertificateInvalidError
+	if !errors.As(err, &ua) && !errors.As(err, &he) && !errors.As(err, &ci) {
+		return
+	}
+	log.Println("HINT: axapi: the certificate of Thunder " + d.Address + " could not be verified. For a self-signed " +
+		"certificate, pin it with TLSConfig.Fingerprint (the SHA-256 fingerprint of the certificate), or set " +
+		"TLSConfig.CAFile to the CA that signed it")
+}
+
+// httpClient returns the http.Client to use for this Device
+//-----------------------------------------------------------------------------
+func (d Device) httpClient() (*http.Client, error) {
+	if d.Client != nil {
+		return d.Client, nil
+	}
+	key := clientKey{HTTP: d.HTTP.withDefaults(), TLS: d.TLS}
+	if c, ok := clients.Load(key); ok {
+		return c.(*http.Client), nil
+	}
+
+	tc, err := key.TLS.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	cc := key.HTTP
+	tr := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   cc.DialTimeout,
+			KeepAlive: cc.KeepAlive,
+		}).DialContext,
+		TLSClientConfig:     tc,
+		TLSHandshakeTimeout: cc.TLSHandshakeTimeout,
+		IdleConnTimeout:     cc.IdleConnTimeout,
+		MaxIdleConns:        cc.MaxIdleConns,
+		MaxIdleConnsPerHost: cc.MaxIdleConns,
+	}
+	c, _ := clients.LoadOrStore(key, &http.Client{Transport: tr, Timeout: cc.Timeout})
+	return c.(*http.Client), nil
+}
+
+// CurrentToken returns the auth token the session is using now. Device.Token is
+// the one handed out by Login(), and goes stale once the session is renewed.
+//-----------------------------------------------------------------------------
+func (d Device) CurrentToken() string {
+	return d.token()
+}
+
+// token returns the current auth token for the Device
+//-----------------------------------------------------------------------------
+func (d Device) token() string {
+	if d.session == nil {
+		return d.Token
+	}
+	d.session.mu.Lock()
+	defer d.session.mu.Unlock()
+	return d.session.token
+}
+
+// renew logs in again with the Device credentials, unless another caller has
+// already done so since 'old' was handed out -- concurrent callers that all
+// hit an expired token share a single re-login.
+//-----------------------------------------------------------------------------
+func (s *session) renew(ctx context.Context, d Device, old string) (string, error) {
+	tok, renewed, err := s.relogin(ctx, d, old)
+	// -- The hooks are called without s.mu held, so they are free to make API calls
+	if err != nil {
+		if d.Hooks.OnRenewFailed != nil {
+			d.Hooks.OnRenewFailed(d, err)
+		}
+		return "", err
+	}
+	if renewed {
+		d.Token = tok
+		if d.Hooks.OnRenew != nil {
+			d.Hooks.OnRenew(d)
+		}
+	}
+	return tok, nil
+}
+
+// relogin does the work of renew() with s.mu held. Returns the current token
+// as-is, and false, if it has already been renewed.
+//-----------------------------------------------------------------------------
+func (s *session) relogin(ctx context.Context, d Device, old string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != old && s.token != "" {
+		return s.token, false, nil
+	}
+
+	tok, err := d.authToken(ctx)
+	if err == nil && s.partition != "" {
+		// A new token starts out in the shared partition. s.mu is held, so this
+		// can't go through _restCall().
+		b, _ := json.Marshal(partitionPayload(s.partition))
+		var body []byte
+		body, _, err = _doCall(ctx, d, tok, "/active-partition", "POST", "", strings.NewReader(string(b)))
+		if err == nil {
+			_, err = d.chkResp(body)
+		}
+	}
+	if err != nil {
+		return "", false, err
+	}
+	s.token = tok
+	return tok, true, nil
+}
+
+// _restCall is the basic API callout function. If the Thunder refuses the auth
+// token (a 401 or 403, or ACOS error 1009, see ErrAuth), the session is renewed
+// and the call is retried once.
+//-----------------------------------------------------------------------------
+func _restCall(ctx context.Context, d Device, url string, method string, payload *strings.Reader) ([]byte, error) {
+	return _restCallType(ctx, d, url, method, "", payload)
+}
+
+// _restCallType is _restCall() with the payload Content-Type set, IE> for
+// multipart file uploads. An empty ctype is the same as _restCall().
+//-----------------------------------------------------------------------------
+func _restCallType(ctx context.Context, d Device, url string, method string, ctype string, payload *strings.Reader) ([]byte, error) {
+	var tok string
+	if url != "/auth" {
+		tok = d.token()
+		if tok == "" {
+			return []byte{}, errors.New("No A10 Auth Token! You must Login() before calling other API calls")
+		}
 	}
 	if payload == nil {
 		payload = strings.NewReader("")
 	}
 
-	// Skip insecure SSL verify returns -- lots of Thunders don't have this set.
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	body, _, err := _doCall(ctx, d, tok, url, method, ctype, payload)
+	expired := errors.Is(err, ErrAuth)
+	if err == nil {
+		// -- ACOS may also refuse the token with a "fail" response status
+		_, e := d.chkResp(body)
+		expired = errors.Is(e, ErrAuth)
+	}
+	if expired && d.session != nil && url != "/auth" && url != "/logoff" {
+		if d.Hooks.OnExpired != nil {
+			d.Hooks.OnExpired(d, url)
+		}
+		tok, err = d.session.renew(ctx, d, tok)
+		if err != nil {
+			return []byte{}, err
+		}
+		payload.Seek(0, io.SeekStart)
+		body, _, err = _doCall(ctx, d, tok, url, method, ctype, payload)
+	}
+	return body, err
+}
+
+// _doCall makes a single API call with the given auth token
+//-----------------------------------------------------------------------------
+func _doCall(ctx context.Context, d Device, tok string, url string, method string, ctype string, payload *strings.Reader) ([]byte, int, error) {
+	var body []byte
+	u := "https://" + d.Address + "/axapi/v3" + url
+	if method == "" {
+		method = "GET"
 	}
-	client := &http.Client{Transport: tr}
+
 	// set the HTTPS request
-	req, err := http.NewRequest(method, u, payload)
+	req, err := http.NewRequestWithContext(ctx, method, u, payload)
 	if err != nil {
-		return []byte{}, err
+		return []byte{}, 0, err
 	}
 
-	if url == "/clideploy" {
+	if ctype != "" {
+		req.Header.Add("Content-Type", ctype)
+	} else if url == "/clideploy" {
 		req.Header.Add("Content-Type", "text/plain")
 	} else {
 		req.Header.Add("Content-Type", "application/json")
 	}
-	if d.Token != "" {
-		req.Header.Add("Authorization", d.Token)
+	if tok != "" {
+		req.Header.Add("Authorization", tok)
 	}
 
+	client, err := d.httpClient()
+	if err != nil {
+		return []byte{}, 0, err
+	}
+	start := time.Now()
 	res, err := client.Do(req)
+	if d.Hooks.OnCall != nil {
+		status := 0
+		if err == nil {
+			status = res.StatusCode
+		}
+		d.Hooks.OnCall(d, method, url, status, time.Since(start))
+	}
 
 	if err != nil {
-		return []byte{}, err
+		certHint(d, err)
+		return []byte{}, 0, err
 	}
 	if res.Body != nil {
 		defer res.Body.Close()
 	}
 
+	body, err = ioutil.ReadAll(res.Body)
 	//fmt.Println(res)
 	if res.StatusCode > 299 { // Check for API Errors on Call
-		return []byte{}, errors.New(res.Status)
+		return []byte{}, res.StatusCode, newAPIError(res.StatusCode, method, url, body)
 	}
-
-	body, err = ioutil.ReadAll(res.Body)
 	if err != nil {
-		return []byte{}, err
+		return []byte{}, res.StatusCode, err
 	}
-	return body, nil
+	return body, res.StatusCode, nil
 }
 
 // chkResp -- Check the response from _restCall() for "fail"  response status
@@ -99,15 +429,94 @@ func (d Device) chkResp(b []byte) (bool, error) {
 	if gjson.GetBytes(b, "response.status").Exists() {
 		if gjson.GetBytes(b, "response.status").Str == "fail" {
 			//fmt.Println(string(body))
-			return true, errors.New(gjson.GetBytes(b, "response.err.msg").Str)
+			return true, newAPIError(http.StatusOK, "", "", b)
 		}
 	}
 	return false, nil
 }
 
+// sendJSON marshals v (if not nil) as the payload of an API call, and checks
+// the response for a "fail" status
+//-----------------------------------------------------------------------------
+func (d Device) sendJSON(ctx context.Context, method string, url string, v interface{}) ([]byte, error) {
+	var pl *strings.Reader
+	if v != nil {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return []byte{}, err
+		}
+		pl = strings.NewReader(string(b))
+	}
+	body, err := _restCall(ctx, d, url, method, pl)
+	if err != nil {
+		return []byte{}, err
+	}
+	if e, msg := d.chkResp(body); e {
+		return []byte{}, msg
+	}
+	return body, nil
+}
+
+// sendFile uploads a file as a multipart/form-data POST, the way the aXAPI '/file/...'
+// calls want it: a 'json' part describing the file, and a 'file' part holding it.
+//-----------------------------------------------------------------------------
+func (d Device) sendFile(ctx context.Context, url string, v interface{}, filename string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	js, err := json.Marshal(v)
+	if err != nil {
+		return []byte{}, err
+	}
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", `form-data; name="json"; filename="blob"`)
+	h.Set("Content-Type", "application/json")
+	pw, err := mw.CreatePart(h)
+	if err != nil {
+		return []byte{}, err
+	}
+	pw.Write(js)
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return []byte{}, err
+	}
+	fw.Write(data)
+	if err = mw.Close(); err != nil {
+		return []byte{}, err
+	}
+
+	body, err := _restCallType(ctx, d, url, "POST", mw.FormDataContentType(), strings.NewReader(buf.String()))
+	if err != nil {
+		return []byte{}, err
+	}
+	if e, msg := d.chkResp(body); e {
+		return []byte{}, msg
+	}
+	return body, nil
+}
+
+// getJSON GETs url, and unmarshals the object under 'key' in the response into v
+//-----------------------------------------------------------------------------
+func (d Device) getJSON(ctx context.Context, url string, key string, v interface{}) error {
+	body, err := d.sendJSON(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	r := gjson.GetBytes(body, key)
+	if !r.Exists() {
+		return &APIError{StatusCode: http.StatusNotFound, Message: "'" + key + "' not found in response", Method: "GET", Path: url}
+	}
+	return json.Unmarshal([]byte(r.Raw), v)
+}
+
 // Login to the A10 Thunder device
 //-----------------------------------------------------------------------------
 func (d Device) Login() (Device, error) {
+	return d.LoginContext(context.Background())
+}
+
+// LoginContext -- Login() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) LoginContext(ctx context.Context) (Device, error) {
 	if d.Username == "" {
 		d.Username = "admin"
 	}
@@ -115,21 +524,53 @@ func (d Device) Login() (Device, error) {
 		d.Password = "a10"
 	}
 
-	payload := strings.NewReader("{\n\"credentials\": {\n\"username\": \"" + d.Username + "\",\n\"password\": \"" + d.Password + "\"\n}\n}")
-
-	body, err := _restCall(d, "/auth", "POST", payload)
+	tok, err := d.authToken(ctx)
 	if err != nil {
 		return d, err
 	}
 
-	d.Token = "A10 " + gjson.GetBytes(body, "authresponse.signature").Str
+	d.Token = tok
+	if d.session == nil {
+		d.session = &session{}
+	}
+	d.session.mu.Lock()
+	d.session.token = tok
+	d.session.partition = ""
+	d.session.mu.Unlock()
+	if d.Partition != "" {
+		if err := d.SetActivePartitionContext(ctx, d.Partition); err != nil {
+			return d, err
+		}
+	}
+	if d.Hooks.OnLogin != nil {
+		d.Hooks.OnLogin(d)
+	}
 	return d, nil
 }
 
+// authToken logs in with the Device credentials, and returns the new auth token
+//-----------------------------------------------------------------------------
+func (d Device) authToken(ctx context.Context) (string, error) {
+	payload := strings.NewReader("{\n\"credentials\": {\n\"username\": \"" + d.Username + "\",\n\"password\": \"" + d.Password + "\"\n}\n}")
+
+	body, err := _restCall(ctx, d, "/auth", "POST", payload)
+	if err != nil {
+		return "", err
+	}
+
+	return "A10 " + gjson.GetBytes(body, "authresponse.signature").Str, nil
+}
+
 // GetHostname gets the hostname that the Thunder Device has currently assigned.
 //-----------------------------------------------------------------------------
 func (d Device) GetHostname() (Device, error) {
-	body, err := _restCall(d, "/hostname", "GET", nil)
+	return d.GetHostnameContext(context.Background())
+}
+
+// GetHostnameContext -- GetHostname() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetHostnameContext(ctx context.Context) (Device, error) {
+	body, err := _restCall(ctx, d, "/hostname", "GET", nil)
 	if err != nil {
 		return d, err
 	}
@@ -141,7 +582,13 @@ func (d Device) GetHostname() (Device, error) {
 // GetVersion retrieves ACOS version info
 //-----------------------------------------------------------------------------
 func (d Device) GetVersion() (Device, error) {
-	body, err := _restCall(d, "/version/oper", "GET", nil)
+	return d.GetVersionContext(context.Background())
+}
+
+// GetVersionContext -- GetVersion() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetVersionContext(ctx context.Context) (Device, error) {
+	body, err := _restCall(ctx, d, "/version/oper", "GET", nil)
 	if err != nil {
 		return d, err
 	}
@@ -156,7 +603,13 @@ func (d Device) GetVersion() (Device, error) {
 // GetVirtType will return a string of the virtualization type, if available
 //-----------------------------------------------------------------------------
 func (d Device) GetVirtType() (string, error) {
-	body, err := _restCall(d, "/version/oper", "GET", nil)
+	return d.GetVirtTypeContext(context.Background())
+}
+
+// GetVirtTypeContext -- GetVirtType() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) GetVirtTypeContext(ctx context.Context) (string, error) {
+	body, err := _restCall(ctx, d, "/version/oper", "GET", nil)
 	if err != nil {
 		return "", err
 	}
@@ -167,7 +620,13 @@ func (d Device) GetVirtType() (string, error) {
 // Logoff - terminates the current API session
 //-----------------------------------------------------------------------------
 func (d Device) Logoff() (Device, error) {
-	body, err := _restCall(d, "/logoff", "GET", nil)
+	return d.LogoffContext(context.Background())
+}
+
+// LogoffContext -- Logoff() with a context.Context for cancellation & deadlines
+//-----------------------------------------------------------------------------
+func (d Device) LogoffContext(ctx context.Context) (Device, error) {
+	body, err := _restCall(ctx, d, "/logoff", "GET", nil)
 	if err != nil {
 		return d, err
 	}
@@ -176,5 +635,13 @@ func (d Device) Logoff() (Device, error) {
 	}
 
 	d.Token = ""
+	if d.session != nil {
+		d.session.mu.Lock()
+		d.session.token = ""
+		d.session.mu.Unlock()
+	}
+	if d.Hooks.OnLogoff != nil {
+		d.Hooks.OnLogoff(d)
+	}
 	return d, nil
 }
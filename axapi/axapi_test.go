@@ -5,12 +5,16 @@
 package axapi
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 var Hostname string
@@ -22,6 +26,7 @@ var Version string
 var Hardware string
 var BootFrom string
 var SerialNumber string
+var TLS TLSConfig
 
 func setup() Device {
 	var d Device
@@ -34,6 +39,7 @@ func setup() Device {
 	d.Hardware = Hardware
 	d.BootFrom = BootFrom
 	d.SerialNumber = SerialNumber
+	d.TLS = TLS
 	return d
 }
 
@@ -95,6 +101,9 @@ func TestMain(m *testing.M) {
 	d.Address = os.Getenv("A10IP")
 	d.Username = "admin"
 	d.Password = "a10"
+	// Pin the test Thunder's cert with A10FINGERPRINT, else skip cert checks
+	d.TLS.Fingerprint = os.Getenv("A10FINGERPRINT")
+	d.TLS.Insecure = d.TLS.Fingerprint == ""
 	d, err := d.Login()
 	if err != nil {
 		panic(err)
@@ -107,6 +116,7 @@ func TestMain(m *testing.M) {
 	Password = d.Password
 	Address = d.Address
 	Token = d.Token
+	TLS = d.TLS
 	Hostname = ""
 
 	// --- Run the Tests ---
@@ -156,3 +166,96 @@ func TestGetVirtType(t *testing.T) {
 	notErr(t, err)
 	fmt.Println("VirtType: " + f)
 }
+
+func TestGetVersionContext(t *testing.T) {
+	d := setup()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := d.GetVersionContext(ctx) // this SHOULD err
+	isErr(t, err, "Uncaught test for cancelled context")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	d, err = d.GetVersionContext(ctx)
+	notErr(t, err)
+	assertNot(t, d.Version, "")
+}
+
+func TestBadFingerprint(t *testing.T) {
+	d := setup()
+	d.TLS = TLSConfig{Fingerprint: "00:11:22"}
+	_, err := d.GetVersion() // this SHOULD err
+	isErr(t, err, "Uncaught test for invalid fingerprint")
+	d.TLS = TLSConfig{Fingerprint: strings.Repeat("00", 32)}
+	_, err = d.GetVersion() // this SHOULD err
+	isErr(t, err, "Uncaught test for fingerprint mismatch")
+	d.TLS = TLSConfig{Fingerprint: strings.Repeat("00", 32), Insecure: true}
+	_, err = d.GetVersion() // this SHOULD err
+	isErr(t, err, "Uncaught test for Insecure with a fingerprint")
+}
+
+func TestRenewHooks(t *testing.T) {
+	d := setup()
+	var renewed Device
+	d.Hooks.OnRenew = func(rd Device) {
+		// -- API calls from a hook must not deadlock on the session
+		_, err := rd.GetVersion()
+		notErr(t, err)
+		renewed = rd
+	}
+	d, err := d.Login()
+	notErr(t, err)
+	defer d.Logoff()
+	d.session.mu.Lock()
+	d.session.token = "A10 expired"
+	d.session.mu.Unlock()
+
+	done := make(chan error)
+	go func() {
+		_, err := d.GetVersion()
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		notErr(t, err)
+	case <-time.After(30 * time.Second):
+		t.Fatal("Session renew deadlocked")
+	}
+	assertNot(t, renewed.Token, "")
+	assert(t, renewed.Token, d.CurrentToken())
+	assertNot(t, d.Token, d.CurrentToken())
+
+	// -- ACOS doesn't always refuse a stale token with a 401
+	refusals := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"401", http.StatusUnauthorized, ""},
+		{"403", http.StatusForbidden, `{"response":{"status":"fail","err":{"code":1009,"msg":"Invalid session ID"}}}`},
+		{"1009", http.StatusOK, `{"response":{"status":"fail","err":{"code":1009,"msg":"Invalid session ID"}}}`},
+	}
+	for _, r := range refusals {
+		logins := 0
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.URL.Path == "/axapi/v3/auth":
+				logins++
+				fmt.Fprintf(w, `{"authresponse":{"signature":"t%d"}}`, logins)
+			case req.Header.Get("Authorization") == "A10 t1":
+				w.WriteHeader(r.status)
+				fmt.Fprint(w, r.body)
+			default:
+				fmt.Fprint(w, `{"version":{"oper":{"sw-version":"5.2.1"}}}`)
+			}
+		}))
+		rd := Device{Address: srv.Listener.Addr().String(), Username: "admin", Password: "a10", TLS: TLSConfig{Insecure: true}}
+		rd, err := rd.Login()
+		notErr(t, err)
+		vd, err := rd.GetVersion()
+		if err != nil || vd.Version != "5.2.1" || logins != 2 {
+			t.Errorf("%s: got %q, %d logins, %v", r.name, vd.Version, logins, err)
+		}
+		srv.Close()
+	}
+}
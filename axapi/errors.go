@@ -0,0 +1,107 @@
+//
+//  errors.go  --  Typed aXAPI errors
+//
+//  John D. Allen
+//  Sr. Solutions Engineer
+//  A10 Networks, Inc.
+//
+//  Copyright A10 Networks (c) 2020, All Rights Reserved.
+//
+
+package axapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// APIError is returned when the Thunder refuses an API call. It carries the HTTP
+// status along with the ACOS error code & message from the response body.
+// Use errors.Is() with ErrNotFound, ErrAuth or ErrConflict (or the IsNotFound(),
+// IsAuth() & IsConflict() helpers) to find out what kind of failure it was.
+type APIError struct {
+	StatusCode int    // HTTP status code, 200 for a "fail" response status
+	Code       int    // ACOS error code, if any
+	Message    string // ACOS error message, or the HTTP status if there wasn't one
+	Method     string
+	Path       string // aXAPI path, IE> /slb/virtual-server/ws-vip
+}
+
+// Sentinel errors for use with errors.Is()
+var (
+	ErrNotFound = errors.New("axapi: object not found")
+	ErrAuth     = errors.New("axapi: not authorized")
+	ErrConflict = errors.New("axapi: object already exists")
+)
+
+// ACOS error codes that mean the same thing as the sentinel errors
+const (
+	acosNotFound = 1023460352 // Object specified does not exist
+	acosExists   = 1405       // Object already exists
+	acosBadToken = 1009       // Invalid session ID
+)
+
+// Error -- the error interface
+//-----------------------------------------------------------------------------
+func (e *APIError) Error() string {
+	s := "aXAPI"
+	if e.Method != "" || e.Path != "" {
+		s += " " + strings.TrimSpace(e.Method+" "+e.Path)
+	}
+	s += ": " + e.Message
+	if e.Code != 0 {
+		s += " (code " + strconv.Itoa(e.Code) + ")"
+	}
+	return s
+}
+
+// Is lets errors.Is() match an APIError against the sentinel errors
+//-----------------------------------------------------------------------------
+func (e *APIError) Is(target error) bool {
+	msg := strings.ToLower(e.Message)
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.Code == acosNotFound || strings.Contains(msg, "does not exist")
+	case ErrAuth:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden || e.Code == acosBadToken
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict || e.Code == acosExists || strings.Contains(msg, "already exist")
+	}
+	return false
+}
+
+// IsNotFound -- Was the error because the object doesn't exist on the Thunder?
+//-----------------------------------------------------------------------------
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsAuth -- Was the error because the session token was refused?
+//-----------------------------------------------------------------------------
+func IsAuth(err error) bool {
+	return errors.Is(err, ErrAuth)
+}
+
+// IsConflict -- Was the error because the object already exists on the Thunder?
+//-----------------------------------------------------------------------------
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// newAPIError builds an APIError from a response status & body
+//-----------------------------------------------------------------------------
+func newAPIError(status int, method string, path string, body []byte) *APIError {
+	e := &APIError{StatusCode: status, Method: method, Path: path}
+	if gjson.ValidBytes(body) {
+		e.Code = int(gjson.GetBytes(body, "response.err.code").Int())
+		e.Message = gjson.GetBytes(body, "response.err.msg").Str
+	}
+	if e.Message == "" {
+		e.Message = strconv.Itoa(status) + " " + http.StatusText(status)
+	}
+	return e
+}
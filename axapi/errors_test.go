@@ -0,0 +1,32 @@
+//
+//  errors.go tests
+//
+
+package axapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	var err error = newAPIError(404, "GET", "/slb/template/server/none", []byte(`{"response": {"status": "fail", "err": {"code": 1023460352, "msg": "Object specified does not exist"}}}`))
+	assert(t, IsNotFound(err), true)
+	assert(t, IsAuth(err), false)
+	assert(t, IsConflict(err), false)
+	var ae *APIError
+	assert(t, errors.As(err, &ae), true)
+	assert(t, ae.Code, 1023460352)
+	assert(t, ae.Path, "/slb/template/server/none")
+
+	err = newAPIError(401, "GET", "/hostname", []byte("not json"))
+	assert(t, IsAuth(err), true)
+	assert(t, err.Error(), "aXAPI GET /hostname: 401 Unauthorized")
+}
+
+func TestNotFound(t *testing.T) {
+	d := setup()
+	_, err := d.GetServerTemplate("axapi-test-no-such-template")
+	isErr(t, err, "Uncaught test for missing template")
+	assert(t, IsNotFound(err), true)
+}
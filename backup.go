@@ -0,0 +1,184 @@
+package main
+
+//
+//  backup.go  --  Running-Config Backups
+//
+//  Before the first change of each pass (for each partition), the running-config is saved to
+//  BACKUP_DIR as <THND_ID>@<partition>@<YYYYMMDD-HHMMSS>.cfg ("shared" for the shared
+//  partition), and only the newest BACKUP_KEEP backups of each are kept. If the backup can't
+//  be made, no changes are made on that pass. THND_ID can't have an '@' in it.
+//
+//  A backup can be put back on demand with:
+//    opaproxy -config <file> -restore <backup file name | latest>
+//  which replays it on the active Thunder node, in the partition the backup was taken from,
+//  and exits. "latest" is the newest backup of any partition.
+//
+
+import (
+	"a10/axapi"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// backupTS is the layout of the timestamp in backup file names
+const backupTS = "20060102-150405"
+
+//---------------------------------------------------------------------------------
+// backupName() -- File name of a backup of the Thunder node's partition
+func backupName(id string, part string, t time.Time) string {
+	if part == "" {
+		part = "shared"
+	}
+	return id + "@" + part + "@" + t.Format(backupTS) + ".cfg"
+}
+
+//---------------------------------------------------------------------------------
+// parseBackupName() -- Split a backup file name made by backupName() into the Thunder
+// ID, partition & timestamp. '@' can't be in either of the names, so the split is exact.
+func parseBackupName(name string) (string, string, string, error) {
+	bad := errors.New("'" + name + "' is not a config backup file name")
+	f := strings.Split(strings.TrimSuffix(name, ".cfg"), "@")
+	if len(f) != 3 || f[0] == "" || f[1] == "" || !strings.HasSuffix(name, ".cfg") {
+		return "", "", "", bad
+	}
+	if _, err := time.Parse(backupTS, f[2]); err != nil {
+		return "", "", "", bad
+	}
+	return f[0], f[1], f[2], nil
+}
+
+//---------------------------------------------------------------------------------
+// listBackups() -- Backup files of the Thunder ID, oldest first. Only those of the
+// partition 'part' are listed, or all of them if 'part' is "*" -- in which case the order
+// is by partition first.
+func listBackups(dir string, id string, part string) ([]string, error) {
+	if part == "" {
+		part = "shared"
+	}
+	fl, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var nn []string
+	for _, f := range fl {
+		fid, fpart, _, err := parseBackupName(f.Name())
+		if f.IsDir() || err != nil || fid != id || (part != "*" && fpart != part) {
+			continue
+		}
+		nn = append(nn, f.Name())
+	}
+	sort.Strings(nn)
+	return nn, nil
+}
+
+//---------------------------------------------------------------------------------
+// rotateBackups() -- Drop the oldest backups of the Thunder ID's partition past 'keep'
+func rotateBackups(dir string, id string, part string, keep int) error {
+	nn, err := listBackups(dir, id, part)
+	if err != nil {
+		return err
+	}
+	for len(nn) > keep {
+		if err := os.Remove(filepath.Join(dir, nn[0])); err != nil {
+			log.Warnf("Error removing old config backup %s: %s\n", nn[0], err)
+		}
+		nn = nn[1:]
+	}
+	return nil
+}
+
+//---------------------------------------------------------------------------------
+// saveBackup() -- Write the running-config of the session's partition to BACKUP_DIR,
+// and drop the oldest backups past BACKUP_KEEP. Returns the file written.
+func saveBackup(d axapi.Device, config Configuration) (string, error) {
+	cfg, err := d.GetRunningConfig()
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(config.BACKUP_DIR, 0700); err != nil {
+		return "", err
+	}
+	fn := filepath.Join(config.BACKUP_DIR, backupName(config.THND_ID, d.Partition, time.Now()))
+	//
+	// Write to a temp file first, so a half written backup never looks like a good one
+	if err = ioutil.WriteFile(fn+".tmp", []byte(cfg), 0600); err != nil {
+		return "", err
+	}
+	if err = os.Rename(fn+".tmp", fn); err != nil {
+		return "", err
+	}
+	return fn, rotateBackups(config.BACKUP_DIR, config.THND_ID, d.Partition, config.BACKUP_KEEP)
+}
+
+//---------------------------------------------------------------------------------
+// backupOnce() -- Save a backup before the first change of a pass. Later calls on the
+// same pass return the result of the first.
+func (c *Cycle) backupOnce(d axapi.Device) error {
+	if c.backedUp {
+		return c.backupErr
+	}
+	c.backedUp = true
+	fn, err := saveBackup(d, c.Config)
+	if err != nil {
+		c.backupErr = errors.New("Error saving config backup: " + err.Error())
+		return c.backupErr
+	}
+	log.Infof("Saved config backup %s\n", fn)
+	return nil
+}
+
+//---------------------------------------------------------------------------------
+// restoreBackup() -- Replay a backup file on the Thunder node, in the partition it was
+// taken from. 'name' is a file in BACKUP_DIR (or a path), or "latest" for the newest
+// backup of any partition.
+func restoreBackup(d axapi.Device, config Configuration, name string) error {
+	fn := name
+	if name == "latest" {
+		nn, err := listBackups(config.BACKUP_DIR, config.THND_ID, "*")
+		if err != nil {
+			return err
+		}
+		var newest string
+		for _, n := range nn {
+			if _, _, ts, _ := parseBackupName(n); ts >= newest {
+				fn, newest = n, ts
+			}
+		}
+		if newest == "" {
+			return errors.New("No config backups found in " + config.BACKUP_DIR)
+		}
+	}
+	id, part, _, err := parseBackupName(filepath.Base(fn))
+	if err != nil {
+		return err
+	}
+	if id != config.THND_ID {
+		return errors.New("'" + filepath.Base(fn) + "' is a config backup of Thunder node " + id + ", not " + config.THND_ID)
+	}
+	if !strings.ContainsRune(fn, os.PathSeparator) {
+		fn = filepath.Join(config.BACKUP_DIR, fn)
+	}
+	cfg, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+
+	cur := d.Partition
+	if cur == "" {
+		cur = "shared"
+	}
+	log.Infof("Restoring config backup %s to partition '%s'\n", fn, part)
+	if part == cur {
+		return d.RestoreConfig(string(cfg))
+	}
+	return d.WithPartition(part, func(pd axapi.Device) error {
+		return pd.RestoreConfig(string(cfg))
+	})
+}
@@ -0,0 +1,82 @@
+//
+//  backup.go tests
+//
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBackupName(t *testing.T) {
+	tests := []struct {
+		name     string
+		id, part string
+		ok       bool
+	}{
+		{"thunder-1@shared@20261019-120000.cfg", "thunder-1", "shared", true},
+		{"thunder-1@p-1@20261019-120000.cfg", "thunder-1", "p-1", true},
+		{"thunder@1-p1@20261019-120000.cfg", "thunder", "1-p1", true},
+		{"thunder-1-20261019-120000.cfg", "", "", false},
+		{"thunder-1@shared@20261019-1200.cfg", "", "", false},
+		{"thunder-1@shared@20261019-120000.cfg.tmp", "", "", false},
+		{"thunder-1@@20261019-120000.cfg", "", "", false},
+		{"a@b@c@20261019-120000.cfg", "", "", false},
+	}
+	for _, tt := range tests {
+		id, part, _, err := parseBackupName(tt.name)
+		if (err == nil) != tt.ok || id != tt.id || part != tt.part {
+			t.Errorf("%s: got %q %q %v", tt.name, id, part, err)
+		}
+	}
+	n := backupName("thunder-1", "", time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	if n != "thunder-1@shared@20261019-120000.cfg" {
+		t.Errorf("backupName() got %s", n)
+	}
+}
+
+func TestRotateBackups(t *testing.T) {
+	dir := t.TempDir()
+	// -- Three backups each of the shared partition & p1 of 'thunder-1', and of the
+	// shared partition of 'thunder', whose ID is a prefix of the other
+	for i := 0; i < 3; i++ {
+		ts := time.Date(2026, 10, 19, 12, i, 0, 0, time.UTC)
+		for _, n := range []string{backupName("thunder-1", "", ts), backupName("thunder-1", "p1", ts), backupName("thunder", "", ts)} {
+			if err := ioutil.WriteFile(filepath.Join(dir, n), []byte("!"), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := rotateBackups(dir, "thunder-1", "p1", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := rotateBackups(dir, "thunder", "shared", 1); err != nil {
+		t.Fatal(err)
+	}
+	fl, _ := ioutil.ReadDir(dir)
+	var got []string
+	for _, f := range fl {
+		got = append(got, f.Name())
+	}
+	want := []string{
+		"thunder-1@p1@20261019-120100.cfg", "thunder-1@p1@20261019-120200.cfg",
+		"thunder-1@shared@20261019-120000.cfg", "thunder-1@shared@20261019-120100.cfg", "thunder-1@shared@20261019-120200.cfg",
+		"thunder@shared@20261019-120200.cfg",
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("after rotation got %v, want %v", got, want)
+	}
+
+	nn, err := listBackups(dir, "thunder-1", "*")
+	if err != nil || len(nn) != 5 {
+		t.Errorf("listBackups(*) got %v %v", nn, err)
+	}
+}
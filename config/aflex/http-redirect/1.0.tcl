@@ -0,0 +1,6 @@
+#
+# http-redirect v1.0 -- Send all plain HTTP requests to HTTPS
+#
+when HTTP_REQUEST {
+  HTTP::redirect "https://[HTTP::host][HTTP::uri]"
+}
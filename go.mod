@@ -1,4 +1,4 @@
-module main
+module a10-opa-proxy
 
 go 1.17
 
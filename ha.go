@@ -0,0 +1,128 @@
+package main
+
+//
+//  ha.go  --  VRRP-A High Availability Pair Handling
+//
+//  When the Thunder nodes are a VRRP-A pair, config has to go to the active unit (config-sync
+//  then copies it to the standby). Set THND_PEER_IP to the standby's address, and THND_VRID
+//  to the VRID to follow (default 0). Each pass, the proxy asks the unit it last used whether
+//  it is still active, and if not, moves over to the peer. A pass is skipped if neither unit
+//  says it is active, or a unit doesn't have the VRID set up.
+//
+//  When THND_FINGERPRINT pins the Thunder's certificate, THND_PEER_FINGERPRINT must pin the
+//  peer's, as the two units don't share a certificate.
+//
+
+import (
+	"a10/axapi"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type haPair struct {
+	devs   []axapi.Device
+	login  []bool // has the unit been logged into?
+	cur    int    // unit used on the last pass
+	vrid   int
+	synced bool // was config-sync in sync on the last check?
+}
+
+//---------------------------------------------------------------------------------
+// newHAPair() -- Set up the Thunder node 'd', and its peer if 'peer' isn't empty.
+// The peer is a copy of 'd' with a different address, and its own certificate
+// fingerprint, 'peerFP', since each unit has its own certificate.
+func newHAPair(d axapi.Device, peer string, peerFP string, vrid int) *haPair {
+	h := &haPair{devs: []axapi.Device{d}, vrid: vrid, synced: true}
+	if peer != "" {
+		p := d
+		p.Address = peer
+		p.TLS.Fingerprint = peerFP
+		p.TLS.ServerName = ""
+		h.devs = append(h.devs, p)
+	}
+	h.login = make([]bool, len(h.devs))
+	return h
+}
+
+//---------------------------------------------------------------------------------
+// connect() -- Log into any unit that isn't logged in yet. Returns the last error.
+func (h *haPair) connect() error {
+	var lerr error
+	for i := range h.devs {
+		if h.login[i] {
+			continue
+		}
+		d, err := h.devs[i].Login()
+		if err != nil {
+			log.Errorf("Error logging into Thunder node %s: %s\n", h.devs[i].Address, err)
+			probes.session(h.devs[i].Address, err)
+			lerr = err
+			continue
+		}
+		h.devs[i] = d
+		h.login[i] = true
+		log.Infof("Connected to Thunder Device %s", d.Address)
+	}
+	return lerr
+}
+
+//---------------------------------------------------------------------------------
+// active() -- Find the unit to apply config to. A single Thunder node is always used.
+func (h *haPair) active() (axapi.Device, bool) {
+	if len(h.devs) == 1 {
+		return h.devs[0], h.login[0]
+	}
+	h.connect()
+	for i := range h.devs {
+		n := (h.cur + i) % len(h.devs)
+		if !h.login[n] {
+			continue
+		}
+		st, err := h.devs[n].GetVRIDState(h.vrid)
+		if err != nil {
+			log.Warnf("Error getting VRRP-A state of Thunder node %s: %s\n", h.devs[n].Address, err)
+			continue
+		}
+		if !st.IsActive() {
+			continue
+		}
+		if n != h.cur {
+			log.Warnf("VRRP-A failover: Thunder node %s is now active for VRID %d\n", h.devs[n].Address, h.vrid)
+			h.cur = n
+		}
+		return h.devs[n], true
+	}
+	log.Errorf("No active Thunder node for VRRP-A VRID %d, skipping this pass\n", h.vrid)
+	return axapi.Device{}, false
+}
+
+//---------------------------------------------------------------------------------
+// checkSync() -- Warn when config-sync says the standby has fallen out of step. Only
+// logs when the state changes.
+func (h *haPair) checkSync(d axapi.Device) {
+	if len(h.devs) == 1 {
+		return
+	}
+	cs, err := d.GetConfigSyncStatus()
+	if err != nil {
+		log.Warnf("Error getting config-sync status from Thunder node %s: %s\n", d.Address, err)
+		return
+	}
+	switch {
+	case !cs.InSync() && h.synced:
+		log.Warnf("Config-sync to peer %s is '%s' (last sync %s)\n", cs.Peer, cs.Status, cs.LastSync)
+	case cs.InSync() && !h.synced:
+		log.Infof("Config-sync to peer %s is back in sync\n", cs.Peer)
+	}
+	h.synced = cs.InSync()
+}
+
+//---------------------------------------------------------------------------------
+// logoff() -- Log off all the units that are logged in
+func (h *haPair) logoff() {
+	for i := range h.devs {
+		if h.login[i] {
+			h.devs[i].Logoff()
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+//
+//  health.go  --  Liveness & Readiness Checks
+//
+//  /healthz (liveness) fails when the reconcile loop is stuck: a pass has been running for
+//  longer than STUCK_AFTER seconds (default 3 x CHECK_INTERVAL), or no pass has finished in
+//  CHECK_INTERVAL + STUCK_AFTER seconds.
+//
+//  /readyz (readiness) fails until there is a logged in aXAPI session to a Thunder node, and
+//  at least one OPA query has worked. A session stops counting when its last aXAPI call got
+//  no response, IE> the Thunder node is unreachable, until a call to it works again.
+//
+//  Both return 200 or 503, with a JSON body listing each check, IE>
+//  {"status": "fail", "checks": [{"name": "thunder-session", "ok": false, "detail": "..."}]}
+//
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// healthState is what the checks are worked out from
+type healthState struct {
+	mu        sync.Mutex
+	interval  time.Duration
+	stuck     time.Duration
+	started   time.Time
+	passStart time.Time // zero when no pass is running
+	passEnd   time.Time
+	sessions  map[string]string // Thunder address -> "" if logged in, else the error
+	opaOK     time.Time         // last OPA query that worked
+	opaErr    string            // last OPA error
+}
+
+// check is the result of one check
+type check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+var probes = &healthState{started: time.Now(), sessions: map[string]string{}}
+
+//---------------------------------------------------------------------------------
+// setup() -- Set the loop timing from the config
+func (h *healthState) setup(config Configuration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.interval = time.Second * config.CHK_INTERVAL
+	h.stuck = time.Second * config.STUCK_AFTER
+}
+
+// passStarted() & passDone() -- Called around each pass of the reconcile loop
+func (h *healthState) passStarted() {
+	h.mu.Lock()
+	h.passStart = time.Now()
+	h.mu.Unlock()
+}
+
+func (h *healthState) passDone() {
+	h.mu.Lock()
+	h.passStart = time.Time{}
+	h.passEnd = time.Now()
+	h.mu.Unlock()
+}
+
+// session() -- Record the state of the session to a Thunder node. A nil error means
+// logged in.
+func (h *healthState) session(addr string, err error) {
+	h.mu.Lock()
+	h.sessions[addr] = ""
+	if err != nil {
+		h.sessions[addr] = err.Error()
+	}
+	h.mu.Unlock()
+}
+
+// call() -- Record the result of an aXAPI call to a Thunder node. Status 0 (no response)
+// marks the session down, and a 2xx marks it up again. Other statuses are left to the
+// login & renew hooks.
+func (h *healthState) call(addr string, status int) {
+	h.mu.Lock()
+	switch {
+	case status == 0:
+		h.sessions[addr] = "no response from the Thunder node"
+	case status >= 200 && status <= 299:
+		h.sessions[addr] = ""
+	}
+	h.mu.Unlock()
+}
+
+// sessionGone() -- The session to a Thunder node was logged off
+func (h *healthState) sessionGone(addr string) {
+	h.mu.Lock()
+	delete(h.sessions, addr)
+	h.mu.Unlock()
+}
+
+// opa() -- Record the result of an OPA query
+func (h *healthState) opa(err error) {
+	h.mu.Lock()
+	if err == nil {
+		h.opaOK = time.Now()
+	} else {
+		h.opaErr = err.Error()
+	}
+	h.mu.Unlock()
+}
+
+//---------------------------------------------------------------------------------
+// liveness() -- Is the reconcile loop still going?
+func (h *healthState) liveness() []check {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	c := check{Name: "reconcile-loop", OK: true}
+	switch {
+	case !h.passStart.IsZero() && now.Sub(h.passStart) > h.stuck:
+		c.OK = false
+		c.Detail = "pass running since " + h.passStart.Format(time.RFC3339)
+	case h.passStart.IsZero() && h.passEnd.IsZero() && now.Sub(h.started) > h.interval+h.stuck:
+		c.OK = false
+		c.Detail = "no pass has finished since starting at " + h.started.Format(time.RFC3339)
+	case h.passStart.IsZero() && !h.passEnd.IsZero() && now.Sub(h.passEnd) > h.interval+h.stuck:
+		c.OK = false
+		c.Detail = "no pass since " + h.passEnd.Format(time.RFC3339)
+	case !h.passEnd.IsZero():
+		c.Detail = "last pass finished " + h.passEnd.Format(time.RFC3339)
+	}
+	return []check{c}
+}
+
+// readiness() -- Is there a Thunder session, and has OPA answered?
+func (h *healthState) readiness() []check {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sc := check{Name: "thunder-session"}
+	var errs []string
+	for a, e := range h.sessions {
+		if e == "" {
+			sc.OK = true
+			sc.Detail = "logged into " + a
+			break
+		}
+		errs = append(errs, a+": "+e)
+	}
+	if !sc.OK {
+		sort.Strings(errs)
+		sc.Detail = "not logged into any Thunder node"
+		if len(errs) > 0 {
+			sc.Detail += ": " + strings.Join(errs, "; ")
+		}
+	}
+
+	oc := check{Name: "opa-query", OK: !h.opaOK.IsZero()}
+	if oc.OK {
+		oc.Detail = "last worked " + h.opaOK.Format(time.RFC3339)
+	} else {
+		oc.Detail = "no OPA query has worked yet"
+		if h.opaErr != "" {
+			oc.Detail += ": " + h.opaErr
+		}
+	}
+	return []check{sc, oc}
+}
+
+//---------------------------------------------------------------------------------
+// writeChecks() -- Send the checks as JSON, with a 503 if any failed
+func writeChecks(w http.ResponseWriter, cl []check) {
+	st, code := "ok", http.StatusOK
+	for _, c := range cl {
+		if !c.OK {
+			st, code = "fail", http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": st, "checks": cl})
+}
+
+// healthzHandler() & readyzHandler() -- Serve /healthz & /readyz
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeChecks(w, probes.liveness())
+}
+
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	writeChecks(w, probes.readiness())
+}
@@ -0,0 +1,116 @@
+//
+//  health.go tests
+//
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLiveness(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name                        string
+		started, passStart, passEnd time.Time
+		ok                          bool
+	}{
+		{"just started", now, time.Time{}, time.Time{}, true},
+		{"no pass since start", now.Add(-time.Minute), time.Time{}, time.Time{}, false},
+		{"pass running", now.Add(-time.Minute), now.Add(-time.Second), now.Add(-2 * time.Second), true},
+		{"pass stuck", now.Add(-time.Minute), now.Add(-time.Minute), time.Time{}, false},
+		{"recent pass", now.Add(-time.Minute), time.Time{}, now.Add(-time.Second), true},
+		{"no recent pass", now.Add(-time.Hour), time.Time{}, now.Add(-time.Minute), false},
+	}
+	for _, tt := range tests {
+		h := &healthState{interval: 10 * time.Second, stuck: 30 * time.Second,
+			started: tt.started, passStart: tt.passStart, passEnd: tt.passEnd}
+		cl := h.liveness()
+		if len(cl) != 1 || cl[0].OK != tt.ok {
+			t.Errorf("%s: got %+v, want ok=%v", tt.name, cl, tt.ok)
+		}
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name         string
+		do           func(h *healthState)
+		session, opa bool
+	}{
+		{"nothing yet", func(h *healthState) {}, false, false},
+		{"logged in", func(h *healthState) {
+			h.session("a", nil)
+			h.opa(nil)
+		}, true, true},
+		{"login failed", func(h *healthState) {
+			h.session("a", errors.New("bad password"))
+			h.opa(errors.New("refused"))
+		}, false, false},
+		{"thunder unreachable", func(h *healthState) {
+			h.session("a", nil)
+			h.call("a", 0)
+			h.opa(nil)
+		}, false, true},
+		{"thunder back", func(h *healthState) {
+			h.session("a", nil)
+			h.call("a", 0)
+			h.call("a", 200)
+		}, true, false},
+		{"error status", func(h *healthState) {
+			h.session("a", nil)
+			h.call("a", 404)
+		}, true, false},
+		{"one unit of a pair down", func(h *healthState) {
+			h.session("a", nil)
+			h.session("b", nil)
+			h.call("b", 0)
+		}, true, false},
+		{"logged off", func(h *healthState) {
+			h.session("a", nil)
+			h.sessionGone("a")
+		}, false, false},
+	}
+	for _, tt := range tests {
+		h := &healthState{sessions: map[string]string{}}
+		tt.do(h)
+		cl := h.readiness()
+		if len(cl) != 2 || cl[0].OK != tt.session || cl[1].OK != tt.opa {
+			t.Errorf("%s: got %+v, want thunder-session=%v opa-query=%v", tt.name, cl, tt.session, tt.opa)
+		}
+	}
+}
+
+func TestWriteChecks(t *testing.T) {
+	tests := []struct {
+		cl     []check
+		code   int
+		status string
+	}{
+		{[]check{{Name: "a", OK: true}}, http.StatusOK, "ok"},
+		{[]check{{Name: "a", OK: true}, {Name: "b"}}, http.StatusServiceUnavailable, "fail"},
+		{[]check{{Name: "a"}, {Name: "b", OK: true}}, http.StatusServiceUnavailable, "fail"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		writeChecks(w, tt.cl)
+		var body struct {
+			Status string  `json:"status"`
+			Checks []check `json:"checks"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != tt.code || body.Status != tt.status || len(body.Checks) != len(tt.cl) {
+			t.Errorf("%+v: got %d %+v", tt.cl, w.Code, body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type got %s", ct)
+		}
+	}
+}
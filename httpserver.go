@@ -0,0 +1,39 @@
+package main
+
+//
+//  httpserver.go  --  Embedded HTTP Server
+//
+//  Serves the proxy's own endpoints on LISTEN_ADDR (default ":8080"):
+//    /metrics  -- Prometheus metrics
+//    /healthz  -- liveness check (see health.go)
+//    /readyz   -- readiness check
+//
+
+import (
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//---------------------------------------------------------------------------------
+// startHTTP() -- Start the HTTP server in the background. Not being able to listen is
+// logged, but doesn't stop the proxy.
+func startHTTP(config Configuration) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", metricsHandler)
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
+	srv := &http.Server{
+		Addr:         config.LISTEN_ADDR,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Infof("HTTP server listening on %s\n", config.LISTEN_ADDR)
+		if err := srv.ListenAndServe(); err != nil {
+			log.Errorf("HTTP server stopped: %s\n", err)
+		}
+	}()
+}
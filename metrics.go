@@ -0,0 +1,293 @@
+package main
+
+//
+//  metrics.go  --  Prometheus Metrics
+//
+//  The proxy's metrics are served at /metrics in the Prometheus text format. To keep the
+//  dependencies down, this is a small registry of its own rather than the Prometheus client
+//  library: counters, gauges & histograms, each with a fixed set of labels.
+//
+
+import (
+	"a10/axapi"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metric is one metric family, IE> all the label values of a counter
+type metric struct {
+	name    string
+	help    string
+	typ     string // "counter", "gauge" or "histogram"
+	labels  []string
+	buckets []float64 // histograms only
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is the value of a metric for one set of label values
+type series struct {
+	lv     []string
+	value  float64  // counter & gauge
+	counts []uint64 // histogram, per bucket (not cumulative)
+	sum    float64
+	count  uint64
+}
+
+var (
+	metricsMu sync.Mutex
+	metrics   []*metric
+)
+
+// Default histogram buckets, in seconds
+var callBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+var cycleBuckets = []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+//---------------------------------------------------------------------------------
+// newMetric() -- Add a metric family to the registry
+func newMetric(typ string, name string, help string, buckets []float64, labels ...string) *metric {
+	m := &metric{name: name, help: help, typ: typ, labels: labels, buckets: buckets, series: map[string]*series{}}
+	metricsMu.Lock()
+	metrics = append(metrics, m)
+	metricsMu.Unlock()
+	return m
+}
+
+// get() -- The series for the label values, made if needed. m.mu must be held.
+func (m *metric) get(lv []string) *series {
+	if len(lv) != len(m.labels) {
+		panic("metric " + m.name + ": wrong number of label values")
+	}
+	k := strings.Join(lv, "\xff")
+	s, ok := m.series[k]
+	if !ok {
+		s = &series{lv: append([]string(nil), lv...)}
+		if m.typ == "histogram" {
+			s.counts = make([]uint64, len(m.buckets))
+		}
+		m.series[k] = s
+	}
+	return s
+}
+
+// add() -- Add to a counter (or gauge)
+func (m *metric) add(v float64, lv ...string) {
+	m.mu.Lock()
+	m.get(lv).value += v
+	m.mu.Unlock()
+}
+
+// inc() -- Add 1 to a counter
+func (m *metric) inc(lv ...string) {
+	m.add(1, lv...)
+}
+
+// set() -- Set a gauge
+func (m *metric) set(v float64, lv ...string) {
+	m.mu.Lock()
+	m.get(lv).value = v
+	m.mu.Unlock()
+}
+
+// observe() -- Add a value to a histogram
+func (m *metric) observe(v float64, lv ...string) {
+	m.mu.Lock()
+	s := m.get(lv)
+	for i, b := range m.buckets {
+		if v <= b {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += v
+	s.count++
+	m.mu.Unlock()
+}
+
+// write() -- Write the metric family in the Prometheus text format
+func (m *metric) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.series) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+	var keys []string
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := m.series[k]
+		if m.typ != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, labelStr(m.labels, s.lv, "", ""), fmtFloat(s.value))
+			continue
+		}
+		var cum uint64
+		for i, b := range m.buckets {
+			cum += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, labelStr(m.labels, s.lv, "le", fmtFloat(b)), cum)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, labelStr(m.labels, s.lv, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", m.name, labelStr(m.labels, s.lv, "", ""), fmtFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", m.name, labelStr(m.labels, s.lv, "", ""), s.count)
+	}
+}
+
+// labelStr() -- IE> {policy="cps",vip="ws-vip"}, with an extra label if 'xn' is set
+func labelStr(names []string, vals []string, xn string, xv string) string {
+	var ll []string
+	for i, n := range names {
+		ll = append(ll, n+"="+strconv.Quote(vals[i]))
+	}
+	if xn != "" {
+		ll = append(ll, xn+"="+strconv.Quote(xv))
+	}
+	if len(ll) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(ll, ",") + "}"
+}
+
+// fmtFloat() -- Format a value the way Prometheus expects
+func fmtFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+//---------------------------------------------------------------------------------
+// metricsHandler() -- Serve all the metrics at /metrics
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metricsMu.Lock()
+	ml := append([]*metric(nil), metrics...)
+	metricsMu.Unlock()
+	for _, m := range ml {
+		m.write(w)
+	}
+}
+
+//---------------------------------------------------------------------------------
+// The proxy's metrics
+var (
+	opaDuration = newMetric("histogram", "opaproxy_opa_request_duration_seconds",
+		"Time taken by OPA queries, by data path.", callBuckets, "path")
+	opaErrors = newMetric("counter", "opaproxy_opa_errors_total",
+		"OPA queries that failed or returned an HTTP error, by data path.", nil, "path")
+	axapiDuration = newMetric("histogram", "opaproxy_axapi_request_duration_seconds",
+		"Time taken by aXAPI calls to the Thunder node, by method & endpoint (route template).", callBuckets, "method", "endpoint")
+	axapiRequests = newMetric("counter", "opaproxy_axapi_requests_total",
+		"aXAPI calls to the Thunder node, by method, endpoint & HTTP status (0 if no response).", nil, "method", "endpoint", "status")
+	changesApplied = newMetric("counter", "opaproxy_changes_total",
+		"Changes made on the Thunder node, by policy, VIP & result.", nil, "policy", "vip", "result")
+	cycleDuration = newMetric("histogram", "opaproxy_cycle_duration_seconds",
+		"Time taken by each pass of the reconcile loop.", cycleBuckets)
+	lastSuccess = newMetric("gauge", "opaproxy_last_success_timestamp_seconds",
+		"Unix time of the last pass of the reconcile loop with no errors.", nil)
+	decidedLimit = newMetric("gauge", "opaproxy_decided_limit",
+		"Current limit decided by OPA for a VIP, by policy & limit name.", nil, "policy", "vip", "limit")
+)
+
+//---------------------------------------------------------------------------------
+// observeOPA() -- Record an OPA query. 'failed' covers HTTP errors as well as 'err'.
+func observeOPA(url string, took time.Duration, failed bool) {
+	path := url
+	if i := strings.Index(path, "/v1/data"); i >= 0 {
+		path = path[i+len("/v1/data"):]
+	}
+	if path == "" {
+		path = "/"
+	}
+	opaDuration.observe(took.Seconds(), path)
+	if failed {
+		opaErrors.inc(path)
+	}
+}
+
+// axapiNamed maps the aXAPI path segments that are followed by an object's name (or
+// number) to the placeholder used for it in the endpoint label. The /file/ endpoints are
+// named the same way, IE> /file/aflex/{name}.
+var axapiNamed = map[string]string{
+	"virtual-server": "{name}",
+	"server":         "{name}",
+	"service-group":  "{name}",
+	"client-ssl":     "{name}",
+	"monitor":        "{name}",
+	"class-list":     "{name}",
+	"aflex-scripts":  "{name}",
+	"aflex":          "{name}",
+	"ssl-cert":       "{name}",
+	"ssl-key":        "{name}",
+	"partition":      "{name}",
+	"port":           "{port}",
+	"member":         "{member}",
+	"extended":       "{id}",
+	"acl":            "{id}",
+	"rules":          "{seq}",
+	"ethernet":       "{ifnum}",
+	"ipv4":           "{entry}",
+	"ipv6":           "{entry}",
+	"str":            "{entry}",
+	"ac":             "{entry}",
+}
+
+// axapiEndpoint() -- The route template of an aXAPI path, IE>
+// /slb/virtual-server/ws-vip/port/443+https -> /slb/virtual-server/{name}/port/{port}
+// so the endpoint label doesn't get a value per object. Query strings are left off, and
+// 'oper' & 'stats' are kept, as in /file/aflex/oper.
+func axapiEndpoint(url string) string {
+	if i := strings.IndexByte(url, '?'); i >= 0 {
+		url = url[:i]
+	}
+	sl := strings.Split(url, "/")
+	for i := 1; i < len(sl); i++ {
+		if ph, ok := axapiNamed[sl[i-1]]; ok && sl[i] != "" && sl[i] != "oper" && sl[i] != "stats" {
+			sl[i] = ph
+		}
+	}
+	return strings.Join(sl, "/")
+}
+
+// observeAXAPI() -- The axapi.SessionHooks.OnCall hook
+func observeAXAPI(d axapi.Device, method string, url string, status int, took time.Duration) {
+	ep := axapiEndpoint(url)
+	axapiDuration.observe(took.Seconds(), method, ep)
+	axapiRequests.inc(method, ep, strconv.Itoa(status))
+}
+
+// countChanges() -- Wrap each planned change so it is counted when made
+func countChanges(v Virtual, changes []Change) {
+	for i := range changes {
+		do := changes[i].Do
+		changes[i].Do = func(d axapi.Device) error {
+			err := do(d)
+			res := "ok"
+			if err != nil {
+				res = "error"
+			}
+			changesApplied.inc(v.Policy, v.Name, res)
+			return err
+		}
+	}
+}
+
+// recordLimits() -- Export the limits in a Decision, if it has any
+func recordLimits(v Virtual, dec Decision) {
+	lr, ok := dec.(LimitReporter)
+	if !ok {
+		return
+	}
+	for n, l := range lr.Limits() {
+		decidedLimit.set(l, v.Policy, v.Name, n)
+	}
+}
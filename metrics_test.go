@@ -0,0 +1,85 @@
+//
+//  metrics.go tests
+//
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAxapiEndpoint(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"/slb/virtual-server/ws-vip", "/slb/virtual-server/{name}"},
+		{"/slb/virtual-server/ws-vip/port/443+https", "/slb/virtual-server/{name}/port/{port}"},
+		{"/slb/virtual-server/ws-vip/stats", "/slb/virtual-server/{name}/stats"},
+		{"/slb/virtual-server", "/slb/virtual-server"},
+		{"/slb/virtual-server/", "/slb/virtual-server/"},
+		{"/slb/service-group/sg1/member/web1+80", "/slb/service-group/{name}/member/{member}"},
+		{"/slb/template/client-ssl/test-tls?detail=true", "/slb/template/client-ssl/{name}"},
+		{"/file/aflex/opa-v1", "/file/aflex/{name}"},
+		{"/file/aflex/oper", "/file/aflex/oper"},
+		{"/file/aflex", "/file/aflex"},
+		{"/file/ssl-cert/test.pem", "/file/ssl-cert/{name}"},
+		{"/file/ssl-key/test.pem", "/file/ssl-key/{name}"},
+		{"/file/class-list/opa-deny", "/file/class-list/{name}"},
+		{"/file/class-list", "/file/class-list"},
+		{"/slb/ssl-cert/oper", "/slb/ssl-cert/oper"},
+		{"/version/oper", "/version/oper"},
+	}
+	for _, tt := range tests {
+		if got := axapiEndpoint(tt.url); got != tt.want {
+			t.Errorf("axapiEndpoint(%s) got %s, want %s", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestMetricWrite(t *testing.T) {
+	tests := []struct {
+		m    *metric
+		do   func(m *metric)
+		want string
+	}{
+		{&metric{name: "c_total", help: "A counter.", typ: "counter", labels: []string{"vip"}, series: map[string]*series{}},
+			func(m *metric) {},
+			""},
+		{&metric{name: "c_total", help: "A counter.", typ: "counter", labels: []string{"vip"}, series: map[string]*series{}},
+			func(m *metric) {
+				m.inc("b")
+				m.inc("a")
+				m.add(2, "b")
+			},
+			"# HELP c_total A counter.\n# TYPE c_total counter\n" +
+				"c_total{vip=\"a\"} 1\nc_total{vip=\"b\"} 3\n"},
+		{&metric{name: "g", help: "A gauge.", typ: "gauge", series: map[string]*series{}},
+			func(m *metric) { m.set(0.5) },
+			"# HELP g A gauge.\n# TYPE g gauge\ng 0.5\n"},
+		{&metric{name: "h_seconds", help: "A histogram.", typ: "histogram", labels: []string{"path"},
+			buckets: []float64{.1, 1}, series: map[string]*series{}},
+			func(m *metric) {
+				m.observe(.05, "/x")
+				m.observe(.5, "/x")
+				m.observe(5, "/x")
+			},
+			"# HELP h_seconds A histogram.\n# TYPE h_seconds histogram\n" +
+				"h_seconds_bucket{path=\"/x\",le=\"0.1\"} 1\n" +
+				"h_seconds_bucket{path=\"/x\",le=\"1\"} 2\n" +
+				"h_seconds_bucket{path=\"/x\",le=\"+Inf\"} 3\n" +
+				"h_seconds_sum{path=\"/x\"} 5.55\n" +
+				"h_seconds_count{path=\"/x\"} 3\n"},
+		{&metric{name: "q_total", help: "Quoted.", typ: "counter", labels: []string{"vip"}, series: map[string]*series{}},
+			func(m *metric) { m.inc(`a"b`) },
+			"# HELP q_total Quoted.\n# TYPE q_total counter\nq_total{vip=\"a\\\"b\"} 1\n"},
+	}
+	for _, tt := range tests {
+		tt.do(tt.m)
+		var b bytes.Buffer
+		tt.m.write(&b)
+		if b.String() != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.m.name, b.String(), tt.want)
+		}
+	}
+}
@@ -19,6 +19,7 @@ package main
 import (
 	"a10/axapi"
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -43,6 +44,8 @@ var OPA_IP string
 var THND_PORT int
 var THND_IP string
 var THND_ID string
+var THND_PEER_IP string
+var RESTORE string
 var CFG_FILE string
 
 //---------------------------------------------------------------------------------
@@ -51,8 +54,9 @@ var CFG_FILE string
 // is brought in as a JSON array.  Using the 'gopkg.in/yaml.v2' package, it seems to
 // pass the JSON just fine into the []Virtual structure.
 type Virtual struct {
-	Name   string `json:"name"`
-	Policy string `json:"policy"`
+	Name      string `json:"name"`
+	Policy    string `json:"policy"`
+	Partition string `json:"partition"` // L3V partition the VIP is in, if not THND_PARTITION
 }
 
 type Configuration struct {
@@ -66,6 +70,33 @@ type Configuration struct {
 	THND_ID      string        `yaml:"THND_ID"`
 	Virts        []Virtual     `yaml:"vs"`
 	CHK_INTERVAL time.Duration `yaml:"CHECK_INTERVAL"`
+	// Limit on each aXAPI call to the Thunder node, in seconds
+	THND_TIMEOUT time.Duration `yaml:"THND_TIMEOUT"`
+	// How to verify the Thunder node's HTTPS certificate
+	THND_CA_FILE     string `yaml:"THND_CA_FILE"`
+	THND_FINGERPRINT string `yaml:"THND_FINGERPRINT"`
+	THND_CERT_FILE   string `yaml:"THND_CERT_FILE"`
+	THND_KEY_FILE    string `yaml:"THND_KEY_FILE"`
+	THND_INSECURE    bool   `yaml:"THND_INSECURE"`
+	// Partition the aXAPI session works in, if not the shared partition
+	THND_PARTITION string `yaml:"THND_PARTITION"`
+	// Standby unit of a VRRP-A pair, and the VRID to follow
+	THND_PEER_IP          string `yaml:"THND_PEER_IP"`
+	THND_PEER_FINGERPRINT string `yaml:"THND_PEER_FINGERPRINT"`
+	THND_VRID             int    `yaml:"THND_VRID"`
+	// Largest fraction of the Thunder's VIPs the 'state' policy may disable per pass
+	STATE_MAX_DISABLE float64 `yaml:"STATE_MAX_DISABLE"`
+	// Smallest fraction of a VIP's members that must be up for its limits to be lowered
+	OPER_MIN_UP float64 `yaml:"OPER_MIN_UP"`
+	// Address the embedded HTTP server (/metrics, /healthz, /readyz) listens on
+	LISTEN_ADDR string `yaml:"LISTEN_ADDR"`
+	// A pass of the reconcile loop taking longer than this (seconds) fails /healthz
+	STUCK_AFTER time.Duration `yaml:"STUCK_AFTER"`
+	// Directory holding the versioned scripts for the 'aflex' policy
+	AFLEX_DIR string `yaml:"AFLEX_DIR"`
+	// Where running-config backups are saved before changes, and how many to keep
+	BACKUP_DIR  string `yaml:"BACKUP_DIR"`
+	BACKUP_KEEP int    `yaml:"BACKUP_KEEP"`
 }
 
 //---------------------------------------------------------------------------------
@@ -102,11 +133,20 @@ func callOPA(url string, method string, payload string) (string, error) {
 	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
+	start := time.Now()
 	rsp, err := cc.Do(req)
+	observeOPA(url, time.Since(start), err != nil || rsp.StatusCode > 299)
 	if err != nil {
+		probes.opa(err)
 		return "", err
 	}
 	defer rsp.Body.Close()
+	if rsp.StatusCode > 299 {
+		err = errors.New("HTTP " + rsp.Status + " from " + url)
+		probes.opa(err)
+		return "", err
+	}
+	probes.opa(nil)
 	buf := new(bytes.Buffer)
 	buf.ReadFrom(rsp.Body)
 	out := buf.String()
@@ -116,16 +156,108 @@ func callOPA(url string, method string, payload string) (string, error) {
 //---------------------------------------------------------------------------------
 // procLoop()
 // This is the main processing loop that checks for OPA Policies, and updates the
-// defined Thunder node as needed.
-func procLoop(d axapi.Device, config Configuration) {
+// defined Thunder node as needed. Returns false if anything went wrong.
+func procLoop(d axapi.Device, config Configuration) bool {
+	ok := true
+	//
+	// VIPs in other partitions are done with the session switched to that
+	// partition, and then switched back.
+	var parts []string
+	virts := map[string][]Virtual{}
+	for _, v := range config.Virts {
+		if _, ok := virts[v.Partition]; !ok {
+			parts = append(parts, v.Partition)
+		}
+		virts[v.Partition] = append(virts[v.Partition], v)
+	}
+	budget := newDisableBudget(0, 0)
+	for _, v := range config.Virts {
+		if v.Policy == "state" {
+			budget = newDisableBudget(countVIPs(d), config.STATE_MAX_DISABLE)
+			break
+		}
+	}
+	for _, part := range parts {
+		if part == "" || part == config.THND_PARTITION {
+			ok = procVirts(d, config, "", virts[part], budget) && ok
+			continue
+		}
+		vv, pn := virts[part], part
+		err := d.WithPartition(part, func(pd axapi.Device) error {
+			ok = procVirts(pd, config, pn, vv, budget) && ok
+			return nil
+		})
+		if err != nil {
+			log.Errorf("Error switching to partition '%s': %s\n", part, err)
+			ok = false
+		}
+	}
+
 	//
-	// lookup config.Virts on Thunder to make sure it/they are there.
+	// Configure callbacks/something for changes on OPA?
+	// It would be better to implement some sort of check of the OPA Data to see if anything has
+	// been changed since the last time this function was run. I can't find anything in the OPA
+	// documentation that would allow to retrieve a timestamp or revision number that could be
+	// used to determine if indeed a new Data set had been uploaded to OPA, and thus would
+	// require a re-run of this function.
+
+	return ok
+}
+
+//---------------------------------------------------------------------------------
+// countVIPs() -- Number of VIPs on the Thunder node, over all its partitions. Returns 0
+// (IE> nothing may be disabled) if they can't all be counted.
+func countVIPs(d axapi.Device) int {
+	pl, err := d.GetPartitionList()
+	if err != nil {
+		log.Errorf("Error counting VIPs, on GetPartitionList(): %s\n", err)
+		return 0
+	}
+	names := []string{"shared"}
+	for _, p := range pl.All.Oper.Partitions {
+		if p.Status == "" || strings.EqualFold(p.Status, "Active") {
+			names = append(names, p.Name)
+		}
+	}
+	cur := d.Partition
+	if cur == "" {
+		cur = "shared"
+	}
+	n := 0
+	for _, part := range names {
+		count := func(pd axapi.Device) error {
+			vl, err := pd.GetVSlist()
+			n += len(vl)
+			return err
+		}
+		if part == cur {
+			err = count(d)
+		} else {
+			err = d.WithPartition(part, count)
+		}
+		if err != nil {
+			log.Errorf("Error counting VIPs in partition '%s': %s\n", part, err)
+			return 0
+		}
+	}
+	return n
+}
+
+//---------------------------------------------------------------------------------
+// procVirts() -- Run the VIPs of one partition through their Policy Handlers. The
+// session must already be in that partition ('part', "" for the session's own). Returns
+// false if anything went wrong.
+func procVirts(d axapi.Device, config Configuration, part string, virts []Virtual, budget *disableBudget) bool {
+	ok := true
+	//
+	// lookup virts on Thunder to make sure it/they are there.
 	vslist, err := d.GetVSlist()
 	if err != nil {
-		log.Errorf("Error on GetVSlist(): %i\n", err)
+		log.Errorf("Error on GetVSlist(): %s\n", err)
+		ok = false
 	}
 	var ff = false
-	for _, v := range config.Virts {
+	for _, v := range virts {
 		for _, t := range vslist {
 			if t.Name == v.Name {
 				ff = true
@@ -138,171 +270,41 @@ func procLoop(d axapi.Device, config Configuration) {
 	}
 
 	//
-	// Query OPA with config.THND_ID for BW Policy rate, if needed
-	//var bwrate int
-	for _, p := range config.Virts {
-		if p.Policy == "bw" {
-			// --
-			// Bandwidth can be controlled on a Thunder node by attaching a "server" template to each server that
-			// is assigned to the Service Group that is attached to the Virtual server. This will require two
-			// different calls to the Thunder node to retrive first the Service Group name from the Virtual Server,
-			// then a call to get the list of 'members' in that Service Group. Then we have to attach the Server Template
-			// that we have created with all the bandwidth limitations to each Server. In the end, the 'slb' section will
-			// look something like this:
-			//
-			// slb template server opa-policy-bw
-			//   bw-rate-limit 1000 resume 800 duration 20
-			// slb server 44.147.45.220 44.147.45.220
-			// 	template server opa-policy-bw
-			// 	port 31721 tcp
-			// slb server 44.147.45.221 44.147.45.221
-			// 	template server opa-policy-bw
-			// 	port 31721 tcp
-			// slb service-group ws-sg tcp
-			// 	health-check ws-mon
-			// 	member 44.147.45.220 31721
-			// 	member 44.147.45.221 31721
-			// slb virtual-server ws-vip 44.147.45.44
-			// 	port 80 http
-			// 		source-nat auto
-			// 		service-group ws-sg
-			//
-			//  Bandwidth Limits are defined as Kbps...so 1000 Kbps = 1 Mbps
-			// --
-			//
-			// Find the policy for the Thunder ID
-			opaurl := "http://" + config.OPA_IP + ":" + strconv.Itoa(config.OPA_PORT) + "/v1/data/net/bwrate"
-			payld := "{\"input\":{\"node\":\"" + config.THND_ID + "\"}}"
-			out, err := callOPA(opaurl, "POST", payld)
-			if err != nil {
-				log.Warnf("No BW Policy found for Thunder node '%s'\n", config.THND_ID)
-			}
-			bwrate := gjson.Get(out, "result").Int()
-			if config.Debug > 7 {
-				fmt.Printf("rate = %d\n", bwrate)
-			}
-
-			//
-			// Configure & Set Template on Thunder node for BW Policy
-			// NOTE: The BW-Resume var (bwrlr) is hard-coded here at 80% of the BW-Rate collected from the
-			// OPA node. This really should be a configuration item.
-			// NOTE: The BW-Duration var (bwrld) is hard-coded here for 20 seconds. This really should be a
-			// configuration item.
-			var resu float32 = 0.8 // This needs to be a config. item -- BW-Resume
-			bwrld := 20            // This also needs to be a config. item  -- BW-Duration
-			bwrlr := int(float32(bwrate) * resu)
-			payload := "{\"server\": {\"name\": \"opa-policy-bw\", \"bw-rate-limit\": " + strconv.Itoa(int(bwrate)) + ", \"bw-rate-limit-resume\": " + strconv.Itoa(bwrlr) + ", \"bw-rate-limit-duration\": " + strconv.Itoa(bwrld) + "} }"
-			// -- First, check to see if Template already exists
-			out, err = d.GetServerTemplate("opa-policy-bw")
-			if err != nil {
-				log.Errorf("Error on GetServerTemplate(): %s\n", err)
-			}
-			if config.Debug > 7 {
-				fmt.Println(">>>" + payload)
-			}
-			if out == "" {
-				log.Info("Creating BW Policy Template...")
-				err = d.CreateServerTemplate(payload)
-				if err != nil {
-					log.Errorf("Bandwidth Policy Template could not be created on Thunder node: %s\n", err)
-				}
-			} else {
-				log.Info("Updating BW Policy Template")
-				err = d.UpdateServerTemplate(payload)
-				if err != nil {
-					log.Errorf("Bandwidth Policy Template could not be updated on Thunder node: %s\n", err)
-				}
-			}
-			//
-			//  Get Service-Group name & parse out members
-
-			//  Go through list of servers and attach BW Template
+	// Run each VIP through the Policy Handler for its policy type
+	cyc := newCycle(config, part, vslist, budget)
+	for _, p := range virts {
+		if _, ok := cyc.findVS(p.Name); ok {
+			cyc.reportHealth(d, p.Name)
 		}
-		//
-		// Query OPA with config.THND_ID for CPS Policy rate, if needed
-		if p.Policy == "cps" {
-			// --
-			// Connection-Rate-Limiting can be configured at an SLB level on a Thunder node by creating a
-			// virtual-server Template and attaching it to the SLB. This will limit the Connections-per-Second
-			// of the SLB down to the service-group members.  This will require an API call to create the
-			// Template, once it has collected the CPS Policy from OPA, and then another API call to attach
-			// the Template to the SLB.  Once done, the 'slb' section will look something like this:
-			//
-			// slb server 44.147.45.220 44.147.45.220
-			// 	port 31721 tcp
-			// slb server 44.147.45.221 44.147.45.221
-			// 	port 31721 tcp
-			// slb service-group ws-sg tcp
-			// 	health-check ws-mon
-			// 	member 44.147.45.220 31721
-			// 	member 44.147.45.221 31721
-			// slb template virtual-server opa-policy-cps
-			//  conn-limit 200
-			//  conn-rate-limit 200
-			// slb virtual-server ws-vip 44.147.45.44
-			//  template virtual-server opa-policy-cps
-			// 	port 80 http
-			// 		source-nat auto
-			// 		service-group ws-sg
-			// --
-			//
-			// Find the policy for the Thunder ID
-			opaurl := "http://" + config.OPA_IP + ":" + strconv.Itoa(config.OPA_PORT) + "/v1/data/net/cpsrate"
-			payld := "{\"input\":{\"node\":\"" + config.THND_ID + "\"}}"
-			out, err := callOPA(opaurl, "POST", payld)
-			if err != nil {
-				log.Warnf("No CPS Policy found for Thunder node '%s'\n", config.THND_ID)
-			}
-			cpsrate := gjson.Get(out, "result").Int()
-			if config.Debug > 7 {
-				fmt.Printf("rate = %d\n", cpsrate)
-			}
-
-			//
-			// Configure & Set Template on Thunder node for CPS Policy
-			cpsv := strconv.Itoa(int(cpsrate))
-			payload := "{\"virtual-server\": {\"name\": \"opa-policy-cps\", \"conn-limit\": " + cpsv + ", \"conn-rate-limit\": " + cpsv + "} }"
-			// -- First, check to see if Template already exists
-			out, err = d.GetVirtualServerTemplate("opa-policy-cps")
-			if err != nil {
-				log.Errorf("Error on GetServerTemplate(): %s\n", err)
-			}
-			// if not, create, else, update
-			if config.Debug > 7 {
-				fmt.Println(">>>" + payload)
-			}
-			if out == "" {
-				log.Info("Creating CPS Policy Template...")
-				err = d.CreateVirtualServerTemplate(payload)
-				if err != nil {
-					log.Errorf("CPS Policy Template could not be created on Thunder node: %s\n", err)
-				}
-			} else {
-				log.Info("Updating CPS Policy Template")
-				err = d.UpdateVirtualServerTemplate(payload)
-				if err != nil {
-					log.Errorf("CPS Policy Template could not be updated on Thunder node: %s\n", err)
-				}
-			}
-
-			//
-			// Add Template to SLB
-			payload = "{\"virtual-server\": {\"template-virtual-server\": \"opa-policy-cps\" } }"
-			err = d.UpdateVirtualServer(p.Name, payload)
-			if err != nil {
-				log.Errorf("Error updating Virtual Server %s: %s\n", p.Name, err)
+		h := policyHandlers[p.Policy]
+		dec, err := h.Query(cyc, p)
+		if err != nil {
+			log.Warn(err)
+			ok = false
+			continue
+		}
+		recordLimits(p, dec)
+		changes, err := h.Plan(d, cyc, p, dec)
+		if err != nil {
+			log.Errorf("Error planning '%s' Policy for Virtual Server %s: %s\n", p.Policy, p.Name, err)
+			ok = false
+			continue
+		}
+		if len(changes) > 0 {
+			if err = cyc.backupOnce(d); err != nil {
+				log.Errorf("Not applying '%s' Policy for Virtual Server %s: %s\n", p.Policy, p.Name, err)
+				ok = false
+				continue
 			}
 		}
+		countChanges(p, changes)
+		err = h.Apply(d, cyc, p, changes)
+		if err != nil {
+			log.Errorf("Error applying '%s' Policy for Virtual Server %s: %s\n", p.Policy, p.Name, err)
+			ok = false
+		}
 	}
-
-	//
-	// Configure callbacks/something for changes on OPA?
-	// It would be better to implement some sort of check of the OPA Data to see if anything has
-	// been changed since the last time this function was run. I can't find anything in the OPA
-	// documentation that would allow to retrieve a timestamp or revision number that could be
-	// used to determine if indeed a new Data set had been uploaded to OPA, and thus would
-	// require a re-run of this function.
-
+	return ok
 }
 
 // RunProcLoop()
@@ -316,11 +318,29 @@ func procLoop(d axapi.Device, config Configuration) {
 // policy Data is changed. Read it here, and if its different, THEN call procLoop(). This will
 // also save lots of log space too, as the Thunder node won't be constantly being updated
 // using aXAPIs.
-func RunProcLoop(d axapi.Device, config Configuration) {
+func RunProcLoop(h *haPair, config Configuration) {
 	// Run forever.....
 	interval := time.Second * config.CHK_INTERVAL
 	for range time.Tick(interval) {
-		procLoop(d, config)
+		runPass(h, config)
+	}
+}
+
+//---------------------------------------------------------------------------------
+// runPass() -- One pass of procLoop() against the active Thunder node
+func runPass(h *haPair, config Configuration) {
+	probes.passStarted()
+	defer probes.passDone()
+	start := time.Now()
+	d, ok := h.active()
+	if !ok {
+		return
+	}
+	ok = procLoop(d, config)
+	h.checkSync(d)
+	cycleDuration.observe(time.Since(start).Seconds())
+	if ok {
+		lastSuccess.set(float64(time.Now().Unix()))
 	}
 }
 
@@ -338,7 +358,7 @@ func main() {
 
 	//
 	// Handle Interrrupts
-	sigchan := make(chan os.Signal)
+	sigchan := make(chan os.Signal, 1)
 	signal.Notify(sigchan, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-sigchan
@@ -354,6 +374,8 @@ func main() {
 	x5 := flag.Int("thunderport", 443, "Thunder node Port")
 	x6 := flag.String("thunderid", "", "Thudner node ID")
 	x7 := flag.String("config", "./config/config.yaml", "Configuration File Path")
+	x8 := flag.String("thunderpeer", "", "IP or FQDN of the VRRP-A peer Thunder node")
+	x9 := flag.String("restore", "", "Restore a config backup ('latest' or file name) and exit")
 	flag.Parse()
 	DEBUG = *x1
 	OPA_IP = *x2
@@ -362,6 +384,8 @@ func main() {
 	THND_PORT = *x5
 	THND_ID = *x6
 	CFG_FILE = *x7
+	THND_PEER_IP = *x8
+	RESTORE = *x9
 
 	//---------------------------------------------------------------------------------
 	// Parse Config File first, then overwrite as needed with Command Line args.
@@ -387,6 +411,34 @@ func main() {
 	if THND_ID != "" {
 		config.THND_ID = THND_ID
 	}
+	if THND_PEER_IP != "" {
+		config.THND_PEER_IP = THND_PEER_IP
+	}
+	if config.STATE_MAX_DISABLE <= 0 {
+		config.STATE_MAX_DISABLE = 0.25
+	}
+	if config.OPER_MIN_UP <= 0 {
+		config.OPER_MIN_UP = 0.5
+	}
+	if config.AFLEX_DIR == "" {
+		config.AFLEX_DIR = "./config/aflex"
+	}
+	if config.BACKUP_DIR == "" {
+		config.BACKUP_DIR = "./backups"
+	}
+	if config.BACKUP_KEEP <= 0 {
+		config.BACKUP_KEEP = 10
+	}
+	if strings.ContainsRune(config.THND_ID, '@') {
+		log.Fatal("THND_ID can't have an '@' in it: " + config.THND_ID)
+	}
+	if config.LISTEN_ADDR == "" {
+		config.LISTEN_ADDR = ":8080"
+	}
+	if config.STUCK_AFTER <= 0 {
+		config.STUCK_AFTER = 3 * config.CHK_INTERVAL
+	}
+	probes.setup(config)
 
 	if config.Debug > 7 {
 		fmt.Printf("debug: %d\nopaip: %s\nopaport: %d\nthunderip: %s\nthunderport: %d\nthunderid: %s\n",
@@ -417,6 +469,12 @@ func main() {
 		log.Fatal("Thunder ID not specified")
 		ff = 1
 	}
+	for _, v := range config.Virts {
+		if _, ok := policyHandlers[v.Policy]; !ok {
+			log.Fatalf("Unknown policy '%s' for Virtual Server '%s' (known policies: %s)", v.Policy, v.Name, strings.Join(policyNames(), ", "))
+			ff = 1
+		}
+	}
 	if ff == 1 {
 		// Fatal error, exit program.
 		os.Exit(1)
@@ -429,13 +487,74 @@ func main() {
 	d.Address = ap
 	d.Username = config.THND_USER
 	d.Password = config.THND_PASSWD
-	d, err = d.Login()
-	if err != nil {
+	d.Partition = config.THND_PARTITION
+	if config.THND_TIMEOUT != 0 {
+		d.HTTP.Timeout = time.Second * config.THND_TIMEOUT
+	}
+	d.TLS = axapi.TLSConfig{
+		CAFile:      config.THND_CA_FILE,
+		Fingerprint: config.THND_FINGERPRINT,
+		CertFile:    config.THND_CERT_FILE,
+		KeyFile:     config.THND_KEY_FILE,
+		Insecure:    config.THND_INSECURE,
+	}
+	d.Hooks = axapi.SessionHooks{
+		OnLogin: func(d axapi.Device) {
+			probes.session(d.Address, nil)
+		},
+		OnLogoff: func(d axapi.Device) {
+			probes.sessionGone(d.Address)
+		},
+		OnExpired: func(d axapi.Device, url string) {
+			log.Warnf("Thunder session expired on call to %s, logging in again\n", url)
+		},
+		OnRenew: func(d axapi.Device) {
+			log.Info("Thunder session renewed")
+			probes.session(d.Address, nil)
+		},
+		OnRenewFailed: func(d axapi.Device, err error) {
+			log.Errorf("Thunder session could not be renewed: %s\n", err)
+			probes.session(d.Address, err)
+		},
+		OnCall: func(d axapi.Device, method string, url string, status int, took time.Duration) {
+			observeAXAPI(d, method, url, status, took)
+			probes.call(d.Address, status)
+		},
+	}
+	peer := ""
+	if config.THND_PEER_IP != "" {
+		peer = config.THND_PEER_IP + ":" + strconv.Itoa(config.THND_PORT)
+		if config.THND_FINGERPRINT != "" && config.THND_PEER_FINGERPRINT == "" {
+			log.Fatal("THND_PEER_FINGERPRINT must be set when THND_FINGERPRINT is used with THND_PEER_IP")
+		}
+	}
+	h := newHAPair(d, peer, config.THND_PEER_FINGERPRINT, config.THND_VRID)
+	// -- One unit of a pair being down is fine; it is retried on each pass.
+	if err = h.connect(); err != nil && (peer == "" || !h.login[0] && !h.login[1]) {
 		log.Fatal(err.Error())
-	} else {
-		log.Info("Connected to Thunder Device")
 	}
-	defer d.Logoff()
+	defer h.logoff()
+
+	//
+	// Restore a config backup on demand, instead of running
+	if RESTORE != "" {
+		ad, ok := h.active()
+		if !ok {
+			log.Fatal("No active Thunder node to restore config to")
+		}
+		if err = restoreBackup(ad, config, RESTORE); err != nil {
+			log.Error(err.Error())
+			h.logoff()
+			ending(1)
+		}
+		log.Info("Config backup restored")
+		h.logoff()
+		ending(0)
+	}
+
+	//
+	// Serve /metrics, /healthz & /readyz
+	startHTTP(config)
 
 	//
 	// Connect to OPA Server
@@ -518,8 +637,8 @@ func main() {
 
 	//
 	// ** Main processing/policy applying loop
-	procLoop(d, config) // Run direct to avoid RunProcLoop() delay on first run.
-	RunProcLoop(d, config)
+	runPass(h, config) // Run direct to avoid RunProcLoop() delay on first run.
+	RunProcLoop(h, config)
 
 	//---------------------------------------------------------------------------------
 	// Exit the Program!
@@ -0,0 +1,260 @@
+package main
+
+//
+//  policy.go  --  The Policy Handler registry. Each policy type the proxy knows how to
+//  implement on a Thunder node (the 'policy' item in a 'vs' config entry) is a PolicyHandler,
+//  registered by name from an init() function in its own policy_<name>.go file.
+//
+//  procLoop() runs each configured VIP through its handler in three phases:
+//    Query  -- ask OPA for the policy decision for the VIP
+//    Plan   -- compare the decision to the Thunder node & work out what needs changing
+//    Apply  -- make those changes on the Thunder node
+//
+
+import (
+	"a10/axapi"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+//---------------------------------------------------------------------------------
+// PolicyHandler is implemented by each policy type. The Decision returned by Query()
+// is only ever handed back to the same handler's Plan(), so each handler is free to
+// use whatever type it likes for it.
+type PolicyHandler interface {
+	Query(c *Cycle, v Virtual) (Decision, error)
+	Plan(d axapi.Device, c *Cycle, v Virtual, dec Decision) ([]Change, error)
+	Apply(d axapi.Device, c *Cycle, v Virtual, changes []Change) error
+}
+
+// Decision is the policy returned from OPA for a VIP
+type Decision interface{}
+
+// LimitReporter may be implemented by a Decision that sets limits on a VIP, so the
+// decided values can be exported as metrics.
+type LimitReporter interface {
+	Limits() map[string]float64
+}
+
+// Change is a single planned update to the Thunder node
+type Change struct {
+	Desc string
+	Do   func(d axapi.Device) error
+}
+
+// Cycle holds the state shared by all handlers over one pass of procLoop()
+type Cycle struct {
+	Config Configuration
+	VSList []axapi.VS
+	// Partition the pass is over, "" for the session's own
+	part string
+	// VIPs the 'state' policy may still disable, shared by all the partitions on this pass
+	budget *disableBudget
+	// Oper state of the VIPs looked at on this pass
+	health map[string]vipHealth
+	// Has the config been backed up on this pass, and did it work?
+	backedUp  bool
+	backupErr error
+}
+
+// vipHealth is the oper state of a VIP, and of the members behind it
+type vipHealth struct {
+	vs        axapi.VSOper
+	up, total int // members of the VIP's service-groups that are up, out of
+	err       error
+}
+
+//---------------------------------------------------------------------------------
+// policyHandlers is the registry of policy types, keyed by the 'policy' config item
+var policyHandlers = map[string]PolicyHandler{}
+
+// RegisterPolicy() -- Add a PolicyHandler to the registry. Meant to be called from init().
+func RegisterPolicy(name string, h PolicyHandler) {
+	if _, ok := policyHandlers[name]; ok {
+		panic("PolicyHandler '" + name + "' registered twice")
+	}
+	policyHandlers[name] = h
+}
+
+// policyNames() -- Sorted list of registered policy types, for error messages.
+func policyNames() []string {
+	var nn []string
+	for n := range policyHandlers {
+		nn = append(nn, n)
+	}
+	sort.Strings(nn)
+	return nn
+}
+
+// disableBudget is how many VIPs the 'state' policy may disable on one pass of
+// procLoop(), counted over all the partitions of the Thunder node
+type disableBudget struct {
+	max int
+	off map[string]bool // <partition>/<VIP> disabled so far
+}
+
+//---------------------------------------------------------------------------------
+// newDisableBudget() -- Allow 'frac' of the Thunder node's 'total' VIPs to be disabled.
+// Rounded up, so a Thunder node with only a few VIPs can still have one disabled.
+func newDisableBudget(total int, frac float64) *disableBudget {
+	return &disableBudget{max: int(math.Ceil(float64(total) * frac)), off: map[string]bool{}}
+}
+
+//---------------------------------------------------------------------------------
+// newCycle() -- Set up the shared state for a pass of procLoop() over one partition
+func newCycle(config Configuration, part string, vslist []axapi.VS, budget *disableBudget) *Cycle {
+	return &Cycle{Config: config, VSList: vslist, part: part, budget: budget, health: map[string]vipHealth{}}
+}
+
+// findVS() -- Look up a VIP by name in the list read from the Thunder node
+func (c *Cycle) findVS(name string) (axapi.VS, bool) {
+	for _, t := range c.VSList {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return axapi.VS{}, false
+}
+
+// disableOK() -- Can another VIP be disabled on this pass? Keeps us from taking
+// down more than config.STATE_MAX_DISABLE of the Thunder node in one go.
+func (c *Cycle) disableOK(vs string) bool {
+	k := c.part + "/" + vs
+	if c.budget.off[k] {
+		return true
+	}
+	if len(c.budget.off) >= c.budget.max {
+		return false
+	}
+	c.budget.off[k] = true
+	return true
+}
+
+// vipHealth() -- Get the oper state of a VIP & its service-group members from the
+// Thunder node. Only looked up once per pass.
+func (c *Cycle) vipHealth(d axapi.Device, name string) vipHealth {
+	if h, ok := c.health[name]; ok {
+		return h
+	}
+	var h vipHealth
+	h.vs, h.err = d.GetVirtualServerOper(name)
+	vs, _ := c.findVS(name)
+	done := map[string]bool{}
+	for _, vp := range vs.Ports {
+		if h.err != nil || vp.SvcGrp == "" || done[vp.SvcGrp] {
+			continue
+		}
+		done[vp.SvcGrp] = true
+		var so axapi.SvcGrpOper
+		so, h.err = d.GetServiceGroupOper(vp.SvcGrp)
+		for _, m := range so.Members {
+			h.total++
+			if m.IsUp() {
+				h.up++
+			}
+		}
+	}
+	c.health[name] = h
+	return h
+}
+
+// String() -- IE> "functional-up (All Up), 2 of 3 members up"
+func (h vipHealth) String() string {
+	s := h.vs.State
+	if h.vs.Raw != "" {
+		s += " (" + h.vs.Raw + ")"
+	}
+	if h.vs.Reason != "" {
+		s += ": " + h.vs.Reason
+	}
+	return s + ", " + strconv.Itoa(h.up) + " of " + strconv.Itoa(h.total) + " members up"
+}
+
+// reportHealth() -- Log the oper state of a VIP, once per pass. Anything not fully
+// up is logged as a warning.
+func (c *Cycle) reportHealth(d axapi.Device, name string) {
+	if _, done := c.health[name]; done {
+		return
+	}
+	h := c.vipHealth(d, name)
+	switch {
+	case h.err != nil:
+		log.Errorf("Error getting oper state of Virtual Server %s: %s\n", name, h.err)
+	case h.vs.State != axapi.OperUp || h.up < h.total:
+		log.Warnf("Virtual Server %s is %s\n", name, h)
+	case c.Config.Debug > 5:
+		log.Infof("Virtual Server %s is %s\n", name, h)
+	}
+}
+
+// tightenOK() -- Can the limits on a VIP be lowered right now? Tightening limits while
+// most of the members are down would only make an outage worse, so this says no
+// when fewer than config.OPER_MIN_UP of them are up (or their state is unknown).
+func (c *Cycle) tightenOK(d axapi.Device, name string, what string) bool {
+	h := c.vipHealth(d, name)
+	if h.err != nil {
+		log.Warnf("Not tightening %s for Virtual Server %s: oper state unknown: %s\n", what, name, h.err)
+		return false
+	}
+	if h.total > 0 && float64(h.up) < float64(h.total)*c.Config.OPER_MIN_UP {
+		log.Warnf("Not tightening %s for Virtual Server %s: it is %s\n", what, name, h)
+		return false
+	}
+	return true
+}
+
+//---------------------------------------------------------------------------------
+// queryOPA() -- POST an input document to one of the 'net' rules on the OPA Server,
+// and return the 'result'. The Thunder ID is always passed as the 'node' input.
+func queryOPA(config Configuration, rule string, input map[string]string) (gjson.Result, error) {
+	in := map[string]string{"node": config.THND_ID}
+	for k, v := range input {
+		in[k] = v
+	}
+	payld, err := json.Marshal(map[string]interface{}{"input": in})
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	opaurl := "http://" + config.OPA_IP + ":" + strconv.Itoa(config.OPA_PORT) + "/v1/data/net/" + rule
+	out, err := callOPA(opaurl, "POST", string(payld))
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	res := gjson.Get(out, "result")
+	if !res.Exists() {
+		return res, undefinedRule(rule)
+	}
+	return res, nil
+}
+
+// undefinedRule is the error from queryOPA() when OPA answered, but has no 'result' for
+// the rule, IE> no policy is defined. Any other error means OPA couldn't be asked.
+type undefinedRule string
+
+func (u undefinedRule) Error() string {
+	return "no result for OPA rule 'net." + string(u) + "'"
+}
+
+// isUndefined() -- Is the error from queryOPA() just an undefined rule?
+func isUndefined(err error) bool {
+	_, ok := err.(undefinedRule)
+	return ok
+}
+
+// applyChanges() -- Make each planned change in order, stopping at the first error
+// since later changes usually depend on earlier ones (IE> bind after create).
+func applyChanges(d axapi.Device, changes []Change) error {
+	for _, ch := range changes {
+		log.Info(ch.Desc)
+		if err := ch.Do(d); err != nil {
+			return errors.New(ch.Desc + ": " + err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+//
+//  policy_acl.go  --  Access-List ('acl') Policy Handler
+//
+//  The ACL Policy lets Security keep the access-lists in front of each VIP in OPA. OPA is asked
+//  about each VIP by name, and is expected to return something like this:
+//
+//  {
+//    "id": 150,
+//    "rules": [
+//      {"action": "permit", "protocol": "tcp", "src": "10.0.0.0/8", "dst": "any", "dst-port": "443", "remark": "corp"},
+//      {"action": "permit", "protocol": "tcp", "src": "any", "src-port": "1024-65535", "dst": "44.147.45.44"},
+//      {"action": "deny", "protocol": "ip", "src": "any", "dst": "any", "remark": "everything else"}
+//    ],
+//    "ports": [443]
+//  }
+//
+//  'id' is an extended access-list ID (100-199). Rules are applied in the order given; a new
+//  access-list is numbered 10, 20, 30... The access-list is bound to the listed 'ports' of the
+//  VIP, or to all of them if there's no 'ports' list. Once done, the config will look something
+//  like this:
+//
+//  access-list 150 10 remark "corp"
+//  access-list 150 10 permit tcp 10.0.0.0 0.255.255.255 any eq 443
+//  ...
+//  slb virtual-server ws-vip 44.147.45.44
+//  	port 443 https
+//  		access-list 150
+//
+//  To keep from disrupting traffic, the access-list is never deleted & re-created or unbound.
+//  Instead, it is patched rule by rule (see axapi.DiffACLRules()). Rules on the Thunder are
+//  matched to the wanted rules by what they do, not by their seq number, so inserting a rule
+//  doesn't touch the ones after it: new rules get a seq number in the gap where they belong, and
+//  are added first; remark changes are made in place; and rules that are no longer wanted are
+//  removed last. A rule that moves is added at its new place before it is removed from the old
+//  one. So there is never a moment where a wanted rule is missing and traffic falls through to
+//  the implicit deny. Only if there's no gap left is the whole list added again after the last
+//  rule, and the old rules then removed.
+//
+
+import (
+	"a10/axapi"
+	"fmt"
+	"strconv"
+
+	"github.com/tidwall/gjson"
+)
+
+type aclPolicy struct{}
+
+// aclDecision is the access-list wanted for a VIP, and the ports to bind it to
+type aclDecision struct {
+	acl   axapi.ACL
+	ports map[int]bool // nil for all ports
+}
+
+func init() {
+	RegisterPolicy("acl", aclPolicy{})
+}
+
+//---------------------------------------------------------------------------------
+// Query() -- Find the ACL policy for the Thunder ID & VIP
+func (aclPolicy) Query(c *Cycle, v Virtual) (Decision, error) {
+	res, err := queryOPA(c.Config, "acl", map[string]string{"vs": v.Name})
+	if err != nil || !res.Get("id").Exists() {
+		return nil, fmt.Errorf("No ACL Policy found for Virtual Server '%s'", v.Name)
+	}
+	if c.Config.Debug > 7 {
+		fmt.Println(">>>" + res.Raw)
+	}
+
+	ad := aclDecision{acl: axapi.ACL{ID: int(res.Get("id").Int())}}
+	if ad.acl.ID < 100 || ad.acl.ID > 199 {
+		return nil, fmt.Errorf("Invalid access-list ID %d in ACL Policy for Virtual Server %s", ad.acl.ID, v.Name)
+	}
+	for _, r := range res.Get("rules").Array() {
+		ad.acl.Rules = append(ad.acl.Rules, axapi.ACLRule{
+			Action:   r.Get("action").Str,
+			Protocol: r.Get("protocol").Str,
+			Src:      r.Get("src").Str,
+			SrcPort:  portStr(r.Get("src-port")),
+			Dst:      r.Get("dst").Str,
+			DstPort:  portStr(r.Get("dst-port")),
+			Remark:   r.Get("remark").Str,
+		}.Normalize())
+	}
+	if res.Get("ports").Exists() {
+		ad.ports = map[int]bool{}
+		for _, p := range res.Get("ports").Array() {
+			ad.ports[int(p.Int())] = true
+		}
+	}
+	return ad, nil
+}
+
+// portStr() -- OPA may hand back a port as a number or a string ("8000-8080")
+func portStr(r gjson.Result) string {
+	if !r.Exists() {
+		return ""
+	}
+	return r.String()
+}
+
+//---------------------------------------------------------------------------------
+// Plan() -- Work out the rule by rule changes to the access-list, and the ports it
+// needs binding to
+func (aclPolicy) Plan(d axapi.Device, c *Cycle, v Virtual, dec Decision) ([]Change, error) {
+	ad := dec.(aclDecision)
+	id := ad.acl.ID
+
+	var changes []Change
+	cur, err := d.GetACL(id)
+	if err != nil && !axapi.IsNotFound(err) {
+		return nil, fmt.Errorf("Error on GetACL(): %s", err)
+	}
+	add, upd, del, derr := axapi.DiffACLRules(cur.Rules, ad.acl.Rules)
+	if derr != nil {
+		return nil, fmt.Errorf("Access-list %d for Virtual Server %s: %s", id, v.Name, derr)
+	}
+	if axapi.IsNotFound(err) {
+		acl := axapi.ACL{ID: id, Rules: add}
+		changes = append(changes, Change{
+			Desc: "Creating ACL Policy access-list " + strconv.Itoa(id) + "...",
+			Do:   func(d axapi.Device) error { return d.CreateACL(acl) },
+		})
+	} else {
+		for _, r := range add {
+			rr := r
+			changes = append(changes, Change{
+				Desc: "Adding rule " + strconv.Itoa(rr.Seq) + " to access-list " + strconv.Itoa(id),
+				Do:   func(d axapi.Device) error { return d.SetACLRule(id, rr) },
+			})
+		}
+		for _, r := range upd {
+			rr := r
+			changes = append(changes, Change{
+				Desc: "Replacing rule " + strconv.Itoa(rr.Seq) + " of access-list " + strconv.Itoa(id),
+				Do:   func(d axapi.Device) error { return d.SetACLRule(id, rr) },
+			})
+		}
+		for _, r := range del {
+			seq := r.Seq
+			changes = append(changes, Change{
+				Desc: "Removing rule " + strconv.Itoa(seq) + " from access-list " + strconv.Itoa(id),
+				Do:   func(d axapi.Device) error { return d.DeleteACLRule(id, seq) },
+			})
+		}
+	}
+
+	//
+	// Bind the access-list to the VIP ports that don't have it yet
+	vs, _ := c.findVS(v.Name)
+	for _, vp := range vs.Ports {
+		if ad.ports != nil && !ad.ports[vp.PortNumber] {
+			continue
+		}
+		bound := false
+		for _, a := range vp.ACLs {
+			bound = bound || a == id
+		}
+		if bound {
+			continue
+		}
+		pnum, proto := vp.PortNumber, vp.Protocol
+		changes = append(changes, Change{
+			Desc: "Binding access-list " + strconv.Itoa(id) + " to Virtual Server " + v.Name + " port " + strconv.Itoa(pnum),
+			Do:   func(d axapi.Device) error { return d.BindACLToVirtualPort(v.Name, pnum, proto, id) },
+		})
+	}
+	return changes, nil
+}
+
+//---------------------------------------------------------------------------------
+// Apply() -- Make the planned changes on the Thunder node
+func (aclPolicy) Apply(d axapi.Device, c *Cycle, v Virtual, changes []Change) error {
+	return applyChanges(d, changes)
+}
@@ -0,0 +1,218 @@
+package main
+
+//
+//  policy_aflex.go  --  aFleX Script ('aflex') Policy Handler
+//
+//  The aFleX Policy lets OPA pick which version of an aFleX script runs on each VIP. The
+//  scripts themselves are kept on the proxy, in a directory per script under AFLEX_DIR
+//  with one file per version:
+//
+//  config/aflex/
+//      http-redirect/
+//          1.0.tcl
+//          1.1.tcl
+//
+//  OPA is asked about each VIP by name, and is expected to return something like this:
+//
+//  {
+//    "script": "http-redirect",
+//    "version": "1.1",
+//    "ports": [80]
+//  }
+//
+//  Versions are letters & digits split by '.'s. The chosen version is uploaded to the Thunder
+//  node as 'opa-<script>-v<version>' (with the '.'s made '_'s), syntax checked, and bound to
+//  the listed 'ports' of the VIP -- or all of them if there's no 'ports' list -- in place of
+//  any other version of the same script. Old versions are left on the Thunder node, so rolling
+//  back in OPA is just a re-bind. A version is never re-uploaded: if the file no longer matches
+//  the Thunder node's copy, the policy fails until the change is given a new version.
+//
+
+import (
+	"a10/axapi"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type aflexPolicy struct{}
+
+// aflexDecision is the aFleX script version wanted on a VIP, and the ports to bind it to
+type aflexDecision struct {
+	script  string
+	version string
+	ports   map[int]bool // nil for all ports
+}
+
+func init() {
+	RegisterPolicy("aflex", aflexPolicy{})
+}
+
+// aflexName() -- The name a script version is given on the Thunder node
+func aflexName(script string, version string) string {
+	return "opa-" + script + "-v" + strings.ReplaceAll(version, ".", "_")
+}
+
+// parseAflexName() -- Split a name made by aflexName() back into the script & version.
+// A version has no '-' in it, so the last "-v" is always the one before the version.
+func parseAflexName(name string) (string, string, bool) {
+	i := strings.LastIndex(name, "-v")
+	if !strings.HasPrefix(name, "opa-") || i < len("opa-") {
+		return "", "", false
+	}
+	script, ver := name[len("opa-"):i], strings.ReplaceAll(name[i+2:], "_", ".")
+	if script == "" || !validAflexVersion(ver) {
+		return "", "", false
+	}
+	return script, ver, true
+}
+
+// validAflexVersion() -- Versions are letters & digits, split by '.'s, IE> "1.2" or "2.0rc1".
+// Anything else could give two versions the same name on the Thunder node.
+func validAflexVersion(ver string) bool {
+	for _, f := range strings.Split(ver, ".") {
+		if f == "" {
+			return false
+		}
+		for _, r := range f {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//---------------------------------------------------------------------------------
+// Query() -- Find the aFleX policy for the Thunder ID & VIP
+func (aflexPolicy) Query(c *Cycle, v Virtual) (Decision, error) {
+	res, err := queryOPA(c.Config, "aflex", map[string]string{"vs": v.Name})
+	if err != nil || !res.Get("script").Exists() {
+		return nil, fmt.Errorf("No aFleX Policy found for Virtual Server '%s'", v.Name)
+	}
+	if c.Config.Debug > 7 {
+		fmt.Println(">>>" + res.Raw)
+	}
+	ad := aflexDecision{script: res.Get("script").Str, version: res.Get("version").String()}
+	if ad.script == "" || strings.ContainsAny(ad.script, `/\`) || strings.Contains(ad.script, "..") || !validAflexVersion(ad.version) {
+		return nil, fmt.Errorf("Invalid aFleX script '%s' version '%s' in Policy for Virtual Server %s", ad.script, ad.version, v.Name)
+	}
+	if res.Get("ports").Exists() {
+		ad.ports = map[int]bool{}
+		for _, p := range res.Get("ports").Array() {
+			ad.ports[int(p.Int())] = true
+		}
+	}
+	return ad, nil
+}
+
+//---------------------------------------------------------------------------------
+// Plan() -- Upload the script version if the Thunder node doesn't have it, and bind it
+// to the VIP ports in place of any other version
+func (aflexPolicy) Plan(d axapi.Device, c *Cycle, v Virtual, dec Decision) ([]Change, error) {
+	ad := dec.(aflexDecision)
+	name := aflexName(ad.script, ad.version)
+	fn := filepath.Join(c.Config.AFLEX_DIR, ad.script, ad.version+".tcl")
+	text, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("aFleX script version not found: %s", err)
+	}
+
+	al, err := d.GetAflexList()
+	if err != nil {
+		return nil, fmt.Errorf("Error on GetAflexList(): %s", err)
+	}
+	have := false
+	for _, a := range al {
+		have = have || a.Name == name
+	}
+	// -- A version is never changed once on the Thunder node, as it may be bound elsewhere
+	// or be what a rollback goes back to. A changed script needs a new version.
+	if have {
+		cur, err := d.GetAflexScript(name)
+		if err != nil {
+			return nil, fmt.Errorf("Error on GetAflexScript(): %s", err)
+		}
+		if aflexText(cur) != aflexText(string(text)) {
+			return nil, fmt.Errorf("aFleX %s on the Thunder node differs from %s, give the changed script a new version", name, fn)
+		}
+		// -- An upload that failed the syntax check on an earlier pass is never bound
+		if err = d.CheckAflex(name); err != nil {
+			return nil, err
+		}
+	}
+
+	// -- A new version is syntax checked right after the upload. applyChanges() stops at
+	// the first error, so the binds below are only made if it passed.
+	var changes []Change
+	if !have {
+		changes = append(changes, Change{
+			Desc: "Uploading aFleX " + name + " from " + fn,
+			Do: func(d axapi.Device) error {
+				if err := d.UploadAflex(name, string(text)); err != nil {
+					return err
+				}
+				return d.CheckAflex(name)
+			},
+		})
+	}
+
+	//
+	// Swap the script version on each of the VIP ports
+	vs, _ := c.findVS(v.Name)
+	for _, vp := range vs.Ports {
+		if ad.ports != nil && !ad.ports[vp.PortNumber] {
+			continue
+		}
+		var old []string
+		bound := false
+		for _, a := range vp.Aflex {
+			if a == name {
+				bound = true
+			} else if s, _, ok := parseAflexName(a); ok && s == ad.script {
+				old = append(old, a)
+			}
+		}
+		if bound && len(old) == 0 {
+			continue
+		}
+		pnum, proto := vp.PortNumber, vp.Protocol
+		desc := "Binding aFleX " + name + " to Virtual Server " + v.Name + " port " + strconv.Itoa(pnum)
+		if bound {
+			desc = "Unbinding old versions of aFleX " + ad.script + " from Virtual Server " + v.Name + " port " + strconv.Itoa(pnum)
+		}
+		// -- The new version is bound before the old ones go, so the port is never
+		// left without the script
+		changes = append(changes, Change{
+			Desc: desc,
+			Do: func(d axapi.Device) error {
+				if !bound {
+					if err := d.BindAflex(v.Name, pnum, proto, name); err != nil {
+						return err
+					}
+				}
+				for _, a := range old {
+					if err := d.UnbindAflex(v.Name, pnum, proto, a); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	}
+	return changes, nil
+}
+
+// aflexText() -- Script text as compared with the Thunder node's copy, which may not
+// keep the same line endings or trailing whitespace
+func aflexText(s string) string {
+	return strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+}
+
+//---------------------------------------------------------------------------------
+// Apply() -- Make the planned changes on the Thunder node
+func (aflexPolicy) Apply(d axapi.Device, c *Cycle, v Virtual, changes []Change) error {
+	return applyChanges(d, changes)
+}
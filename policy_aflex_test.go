@@ -0,0 +1,45 @@
+//
+//  policy_aflex.go tests
+//
+
+package main
+
+import (
+	"testing"
+)
+
+func TestAflexName(t *testing.T) {
+	tests := []struct {
+		name            string
+		script, version string
+		ok              bool
+	}{
+		{"opa-http-v1_0", "http", "1.0", true},
+		{"opa-http-v2-v1", "http-v2", "1", true},
+		{"opa-http-video-v2_0rc1", "http-video", "2.0rc1", true},
+		{"opa-http-v1__0", "", "", false},
+		{"opa-http-v1-0", "", "", false},
+		{"opa--v1", "", "", false},
+		{"http-v1", "", "", false},
+		{"opa-http", "", "", false},
+	}
+	for _, tt := range tests {
+		s, v, ok := parseAflexName(tt.name)
+		if ok != tt.ok || s != tt.script || v != tt.version {
+			t.Errorf("%s: got %q %q %v", tt.name, s, v, ok)
+		}
+		if ok && aflexName(s, v) != tt.name {
+			t.Errorf("%s: aflexName() got %s back", tt.name, aflexName(s, v))
+		}
+	}
+
+	// -- Versions that would give the same name as another are refused
+	for _, v := range []string{"1_0", "1-0", "1..0", ".1", "1.", ""} {
+		if validAflexVersion(v) {
+			t.Errorf("version %q should be invalid", v)
+		}
+	}
+	if !validAflexVersion("1.0") {
+		t.Error("version 1.0 should be valid")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+//
+//  policy_bw.go  --  Bandwidth ('bw') Policy Handler
+//
+//  Bandwidth can be controlled on a Thunder node by attaching a "server" template to each server that
+//  is assigned to the Service Group that is attached to the Virtual server. This will require two
+//  different calls to the Thunder node to retrive first the Service Group name from the Virtual Server,
+//  then a call to get the list of 'members' in that Service Group. Then we have to attach the Server Template
+//  that we have created with all the bandwidth limitations to each Server. In the end, the 'slb' section will
+//  look something like this:
+//
+//  slb template server opa-policy-bw
+//    bw-rate-limit 1000 resume 800 duration 20
+//  slb server 44.147.45.220 44.147.45.220
+//  	template server opa-policy-bw
+//  	port 31721 tcp
+//  slb server 44.147.45.221 44.147.45.221
+//  	template server opa-policy-bw
+//  	port 31721 tcp
+//  slb service-group ws-sg tcp
+//  	health-check ws-mon
+//  	member 44.147.45.220 31721
+//  	member 44.147.45.221 31721
+//  slb virtual-server ws-vip 44.147.45.44
+//  	port 80 http
+//  		source-nat auto
+//  		service-group ws-sg
+//
+//   Bandwidth Limits are defined as Kbps...so 1000 Kbps = 1 Mbps
+//
+
+import (
+	"a10/axapi"
+	"fmt"
+)
+
+type bwPolicy struct{}
+
+// bwDecision is the bandwidth rate decided by OPA
+type bwDecision int64
+
+func init() {
+	RegisterPolicy("bw", bwPolicy{})
+}
+
+// Limits() -- The LimitReporter interface
+func (b bwDecision) Limits() map[string]float64 {
+	return map[string]float64{"bw-rate": float64(b)}
+}
+
+//---------------------------------------------------------------------------------
+// Query() -- Find the BW policy rate for the Thunder ID
+func (bwPolicy) Query(c *Cycle, v Virtual) (Decision, error) {
+	res, err := queryOPA(c.Config, "bwrate", nil)
+	if err != nil {
+		return nil, fmt.Errorf("No BW Policy found for Thunder node '%s': %s", c.Config.THND_ID, err)
+	}
+	bwrate := res.Int()
+	if c.Config.Debug > 7 {
+		fmt.Printf("rate = %d\n", bwrate)
+	}
+	return bwDecision(bwrate), nil
+}
+
+//---------------------------------------------------------------------------------
+// Plan() -- Configure the Template on Thunder node for the BW Policy
+// NOTE: The BW-Resume var (bwrlr) is hard-coded here at 80% of the BW-Rate collected from the
+// OPA node. This really should be a configuration item.
+// NOTE: The BW-Duration var (bwrld) is hard-coded here for 20 seconds. This really should be a
+// configuration item.
+func (bwPolicy) Plan(d axapi.Device, c *Cycle, v Virtual, dec Decision) ([]Change, error) {
+	bwrate := int64(dec.(bwDecision))
+	var resu float32 = 0.8 // This needs to be a config. item -- BW-Resume
+	bwrld := 20            // This also needs to be a config. item  -- BW-Duration
+	bwrlr := int(float32(bwrate) * resu)
+	tpl := axapi.ServerTemplate{
+		Name:                "opa-policy-bw",
+		BWRateLimit:         int(bwrate),
+		BWRateLimitResume:   bwrlr,
+		BWRateLimitDuration: bwrld,
+	}
+	if c.Config.Debug > 7 {
+		fmt.Printf(">>>%+v\n", tpl)
+	}
+
+	// -- First, check to see if Template already exists
+	cur, err := d.GetServerTemplate("opa-policy-bw")
+	if err != nil && !axapi.IsNotFound(err) {
+		return nil, fmt.Errorf("Error on GetServerTemplate(): %s", err)
+	}
+	// -- Don't lower the limit while most of the members are down
+	if tpl.BWRateLimit > 0 && (cur.BWRateLimit == 0 || tpl.BWRateLimit < cur.BWRateLimit) && !c.tightenOK(d, v.Name, "BW Policy") {
+		return nil, nil
+	}
+	var changes []Change
+	if axapi.IsNotFound(err) {
+		changes = append(changes, Change{
+			Desc: "Creating BW Policy Template...",
+			Do:   func(d axapi.Device) error { return d.CreateServerTemplate(tpl) },
+		})
+	} else if tpl.BWRateLimit != cur.BWRateLimit || tpl.BWRateLimitResume != cur.BWRateLimitResume ||
+		tpl.BWRateLimitDuration != cur.BWRateLimitDuration {
+		changes = append(changes, Change{
+			Desc: "Updating BW Policy Template",
+			Do:   func(d axapi.Device) error { return d.UpdateServerTemplate(tpl) },
+		})
+	}
+	//
+	//  Get Service-Group name & parse out members
+
+	//  Go through list of servers and attach BW Template
+	return changes, nil
+}
+
+//---------------------------------------------------------------------------------
+// Apply() -- Make the planned changes on the Thunder node
+func (bwPolicy) Apply(d axapi.Device, c *Cycle, v Virtual, changes []Change) error {
+	return applyChanges(d, changes)
+}
@@ -0,0 +1,208 @@
+package main
+
+//
+//  policy_cps.go  --  Connection Rate ('cps') Policy Handler
+//
+//  Connection-Rate-Limiting can be configured at an SLB level on a Thunder node by creating a
+//  virtual-server Template and attaching it to the SLB. This will limit the Connections-per-Second
+//  of the SLB down to the service-group members.  This will require an API call to create the
+//  Template, once it has collected the CPS Policy from OPA, and then another API call to attach
+//  the Template to the SLB.  Once done, the 'slb' section will look something like this:
+//
+//  slb server 44.147.45.220 44.147.45.220
+//  	port 31721 tcp
+//  slb server 44.147.45.221 44.147.45.221
+//  	port 31721 tcp
+//  slb service-group ws-sg tcp
+//  	health-check ws-mon
+//  	member 44.147.45.220 31721
+//  	member 44.147.45.221 31721
+//  slb template virtual-server opa-policy-cps
+//   conn-limit 5000 reset
+//   conn-rate-limit 200 per 100ms no-logging
+//  slb virtual-server ws-vip 44.147.45.44
+//   template virtual-server opa-policy-cps
+//  	port 80 http
+//  		source-nat auto
+//  		service-group ws-sg
+//
+//  The Connection Limit (total concurrent connections) and the Connection Rate Limit (new
+//  connections per interval) are separate decisions in OPA:  'net.connlimit' and 'net.cpsrate'.
+//  Either one can be left out of the OPA Policy. How the limits behave is set by the optional
+//  'net.cpsopts' decision, which is expected to return something like this:
+//
+//  {
+//    "rate-interval": "100ms",
+//    "conn-limit-action": "reset",
+//    "conn-limit-no-logging": false,
+//    "conn-rate-limit-action": "drop",
+//    "conn-rate-limit-no-logging": true
+//  }
+//
+//  'rate-interval' is either "second" (the default) or "100ms". The '-action' items are either
+//  "drop" (the default) or "reset".
+//
+
+import (
+	"a10/axapi"
+	"fmt"
+
+	"github.com/tidwall/gjson"
+)
+
+type cpsPolicy struct{}
+
+// cpsDecision holds the three CPS related OPA decisions. Any of them may not Exist().
+type cpsDecision struct {
+	cpsrate   gjson.Result
+	connlimit gjson.Result
+	opts      gjson.Result
+}
+
+func init() {
+	RegisterPolicy("cps", cpsPolicy{})
+}
+
+// Limits() -- The LimitReporter interface
+func (cd cpsDecision) Limits() map[string]float64 {
+	l := map[string]float64{}
+	if cd.connlimit.Exists() {
+		l["conn-limit"] = float64(cd.connlimit.Int())
+	}
+	if cd.cpsrate.Exists() {
+		l["conn-rate-limit"] = float64(cd.cpsrate.Int())
+	}
+	return l
+}
+
+//---------------------------------------------------------------------------------
+// Query() -- Find the CPS & Connection Limit policy for the Thunder ID
+func (cpsPolicy) Query(c *Cycle, v Virtual) (Decision, error) {
+	// -- Each of the rules may be undefined, but if OPA can't be asked about any one of
+	// them, the pass is skipped -- the template is replaced as a whole, so going ahead
+	// would drop whatever that rule sets.
+	var dec cpsDecision
+	var err error
+	for _, q := range []struct {
+		rule string
+		res  *gjson.Result
+	}{{"cpsrate", &dec.cpsrate}, {"connlimit", &dec.connlimit}, {"cpsopts", &dec.opts}} {
+		*q.res, err = queryOPA(c.Config, q.rule, nil)
+		if err != nil && !isUndefined(err) {
+			return nil, fmt.Errorf("Error querying OPA for CPS Policy: %s", err)
+		}
+	}
+	if !dec.cpsrate.Exists() && !dec.connlimit.Exists() {
+		return nil, fmt.Errorf("No CPS or Connection Limit Policy found for Thunder node '%s'", c.Config.THND_ID)
+	}
+	if c.Config.Debug > 7 {
+		fmt.Printf("rate = %d, limit = %d, opts = %s\n", dec.cpsrate.Int(), dec.connlimit.Int(), dec.opts.Raw)
+	}
+	return dec, nil
+}
+
+//---------------------------------------------------------------------------------
+// Plan() -- Configure the Template on Thunder node for the CPS Policy, and attach it to the SLB
+func (cpsPolicy) Plan(d axapi.Device, c *Cycle, v Virtual, dec Decision) ([]Change, error) {
+	cd := dec.(cpsDecision)
+	tpl := axapi.VirtualServerTemplate{Name: "opa-policy-cps"}
+	if cd.connlimit.Exists() {
+		tpl.ConnLimit = int(cd.connlimit.Int())
+		if cd.opts.Get("conn-limit-action").Str == "reset" {
+			tpl.ConnLimitReset = 1
+		}
+		if cd.opts.Get("conn-limit-no-logging").Bool() {
+			tpl.ConnLimitNoLogging = 1
+		}
+	}
+	if cd.cpsrate.Exists() {
+		tpl.ConnRateLimit = int(cd.cpsrate.Int())
+		if cd.opts.Get("rate-interval").Str == "100ms" {
+			tpl.RateInterval = "100ms"
+		}
+		if cd.opts.Get("conn-rate-limit-action").Str == "reset" {
+			tpl.ConnRateLimitReset = 1
+		}
+		if cd.opts.Get("conn-rate-limit-no-logging").Bool() {
+			tpl.ConnRateLimitNoLogging = 1
+		}
+	}
+	if c.Config.Debug > 7 {
+		fmt.Printf(">>>%+v\n", tpl)
+	}
+
+	// -- First, check to see if Template already exists
+	cur, err := d.GetVirtualServerTemplate("opa-policy-cps")
+	if err != nil && !axapi.IsNotFound(err) {
+		return nil, fmt.Errorf("Error on GetVirtualServerTemplate(): %s", err)
+	}
+	// -- Don't lower the limits while most of the members are down
+	if cpsTighter(tpl, cur) && !c.tightenOK(d, v.Name, "CPS Policy") {
+		return nil, nil
+	}
+	// if not, create, else, update
+	var changes []Change
+	if axapi.IsNotFound(err) {
+		changes = append(changes, Change{
+			Desc: "Creating CPS Policy Template...",
+			Do:   func(d axapi.Device) error { return d.CreateVirtualServerTemplate(tpl) },
+		})
+	} else if !cpsSame(tpl, cur) {
+		changes = append(changes, Change{
+			Desc: "Updating CPS Policy Template",
+			Do:   func(d axapi.Device) error { return d.UpdateVirtualServerTemplate(tpl) },
+		})
+	}
+
+	//
+	// Add Template to SLB
+	if vs, _ := c.findVS(v.Name); vs.Template != "opa-policy-cps" {
+		changes = append(changes, Change{
+			Desc: "Attaching CPS Policy Template to Virtual Server " + v.Name,
+			Do: func(d axapi.Device) error {
+				return d.UpdateVirtualServerTyped(axapi.VS{Name: v.Name, Template: "opa-policy-cps"})
+			},
+		})
+	}
+	return changes, nil
+}
+
+//---------------------------------------------------------------------------------
+// cpsSame() -- Does the current Template already have the limits the CPS Policy sets?
+// An unset rate-interval is "second" on the Thunder node.
+func cpsSame(tpl axapi.VirtualServerTemplate, cur axapi.VirtualServerTemplate) bool {
+	interval := func(s string) string {
+		if s == "" {
+			return "second"
+		}
+		return s
+	}
+	return tpl.ConnLimit == cur.ConnLimit &&
+		tpl.ConnLimitReset == cur.ConnLimitReset &&
+		tpl.ConnLimitNoLogging == cur.ConnLimitNoLogging &&
+		tpl.ConnRateLimit == cur.ConnRateLimit &&
+		interval(tpl.RateInterval) == interval(cur.RateInterval) &&
+		tpl.ConnRateLimitReset == cur.ConnRateLimitReset &&
+		tpl.ConnRateLimitNoLogging == cur.ConnRateLimitNoLogging
+}
+
+//---------------------------------------------------------------------------------
+// cpsTighter() -- Does the new Template lower either limit from the current one? A
+// limit of 0 is no limit at all.
+func cpsTighter(tpl axapi.VirtualServerTemplate, cur axapi.VirtualServerTemplate) bool {
+	lower := func(n int, o int) bool { return n > 0 && (o == 0 || n < o) }
+	perSec := func(rate int, interval string) int {
+		if interval == "100ms" {
+			return rate * 10
+		}
+		return rate
+	}
+	return lower(tpl.ConnLimit, cur.ConnLimit) ||
+		lower(perSec(tpl.ConnRateLimit, tpl.RateInterval), perSec(cur.ConnRateLimit, cur.RateInterval))
+}
+
+//---------------------------------------------------------------------------------
+// Apply() -- Make the planned changes on the Thunder node
+func (cpsPolicy) Apply(d axapi.Device, c *Cycle, v Virtual, changes []Change) error {
+	return applyChanges(d, changes)
+}
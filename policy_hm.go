@@ -0,0 +1,165 @@
+package main
+
+//
+//  policy_hm.go  --  Health Monitor ('hm') Policy Handler
+//
+//  The Health Monitor Policy lets OPA decide how each of the Service Groups behind a VIP are
+//  health checked. OPA is asked about each Service Group on the VIP by name, and is expected
+//  to return something like this:
+//
+//  {
+//    "method": "http",
+//    "port": 80,
+//    "url": "/health",
+//    "expect": "200",
+//    "interval": 5,
+//    "timeout": 5,
+//    "retry": 3,
+//    "up-retry": 1
+//  }
+//
+//  'method' is one of "http", "tcp" or "icmp". A health monitor is created for each Service
+//  Group and bound to it. Once done, the 'slb' section will look something like this:
+//
+//  health monitor opa-policy-hm-ws-sg
+//    retry 3
+//    up-retry 1
+//    interval 5 timeout 5
+//    method http port 80 expect response-code 200 url GET /health
+//  slb service-group ws-sg tcp
+//  	health-check opa-policy-hm-ws-sg
+//  	member 44.147.45.220 31721
+//  	member 44.147.45.221 31721
+//
+
+import (
+	"a10/axapi"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type hmPolicy struct{}
+
+// hmDecision is the Health Monitor wanted for one Service Group
+type hmDecision struct {
+	sg string
+	hm axapi.HealthMonitor
+}
+
+func init() {
+	RegisterPolicy("hm", hmPolicy{})
+}
+
+//---------------------------------------------------------------------------------
+// Query() -- Find the Health Monitor policy for each Service Group on the VIP
+func (hmPolicy) Query(c *Cycle, v Virtual) (Decision, error) {
+	var hd []hmDecision
+	vs, _ := c.findVS(v.Name)
+	done := map[string]bool{}
+	for _, vp := range vs.Ports {
+		if vp.SvcGrp == "" || done[vp.SvcGrp] {
+			continue
+		}
+		done[vp.SvcGrp] = true
+		res, err := queryOPA(c.Config, "healthcheck", map[string]string{"vs": v.Name, "sg": vp.SvcGrp})
+		if err != nil && !isUndefined(err) {
+			return nil, fmt.Errorf("Error querying OPA for Health Monitor Policy: %s", err)
+		}
+		if err != nil || !res.Get("method").Exists() {
+			log.Warnf("No Health Monitor Policy found for Service Group '%s'\n", vp.SvcGrp)
+			continue
+		}
+		if c.Config.Debug > 7 {
+			fmt.Println(">>>" + res.Raw)
+		}
+		hd = append(hd, hmDecision{
+			sg: vp.SvcGrp,
+			hm: axapi.HealthMonitor{
+				Name:       "opa-policy-hm-" + vp.SvcGrp,
+				Method:     res.Get("method").Str,
+				Port:       int(res.Get("port").Int()),
+				URL:        res.Get("url").Str,
+				ExpectCode: res.Get("expect").String(),
+				Interval:   int(res.Get("interval").Int()),
+				Timeout:    int(res.Get("timeout").Int()),
+				Retry:      int(res.Get("retry").Int()),
+				UpRetry:    int(res.Get("up-retry").Int()),
+			},
+		})
+	}
+	return hd, nil
+}
+
+//---------------------------------------------------------------------------------
+// Plan() -- Create or Update each Health Monitor, and bind it to its Service Group
+func (hmPolicy) Plan(d axapi.Device, c *Cycle, v Virtual, dec Decision) ([]Change, error) {
+	hd := dec.([]hmDecision)
+	if len(hd) == 0 {
+		return nil, nil
+	}
+	sgl, err := d.GetServiceGroups()
+	if err != nil {
+		return nil, fmt.Errorf("Error on GetServiceGroups(): %s", err)
+	}
+
+	var changes []Change
+	for _, h := range hd {
+		hm := h.hm
+		// -- First, check to see if the Health Monitor already exists
+		cur, err := d.GetHealthMonitor(hm.Name)
+		if err != nil && !axapi.IsNotFound(err) {
+			return nil, fmt.Errorf("Error on GetHealthMonitor(): %s", err)
+		}
+		if axapi.IsNotFound(err) {
+			changes = append(changes, Change{
+				Desc: "Creating Health Monitor Policy " + hm.Name + "...",
+				Do:   func(d axapi.Device) error { return d.CreateHealthMonitor(hm) },
+			})
+		} else if !hmSame(hm, cur) {
+			changes = append(changes, Change{
+				Desc: "Updating Health Monitor Policy " + hm.Name,
+				Do:   func(d axapi.Device) error { return d.UpdateHealthMonitor(hm) },
+			})
+		}
+
+		//
+		// Bind the Health Monitor to the Service Group
+		for _, sg := range sgl {
+			if sg.Name == h.sg && sg.Healthcheck != hm.Name {
+				name := sg.Name
+				changes = append(changes, Change{
+					Desc: "Binding Health Monitor " + hm.Name + " to Service Group " + name,
+					Do:   func(d axapi.Device) error { return d.SetServiceGroupHealthCheck(name, hm.Name) },
+				})
+			}
+		}
+	}
+	return changes, nil
+}
+
+//---------------------------------------------------------------------------------
+// hmSame() -- Does the current Health Monitor already match the policy? Timings left
+// unset in the policy get the Thunder node's defaults.
+func hmSame(want axapi.HealthMonitor, cur axapi.HealthMonitor) bool {
+	def := func(n int, d int) int {
+		if n == 0 {
+			return d
+		}
+		return n
+	}
+	want.Interval = def(want.Interval, 5)
+	want.Timeout = def(want.Timeout, 5)
+	want.Retry = def(want.Retry, 3)
+	want.UpRetry = def(want.UpRetry, 1)
+	if want.Method == "http" {
+		want.Port = def(want.Port, 80)
+	}
+	return want == cur
+}
+
+//---------------------------------------------------------------------------------
+// Apply() -- Make the planned changes on the Thunder node
+func (hmPolicy) Apply(d axapi.Device, c *Cycle, v Virtual, changes []Change) error {
+	return applyChanges(d, changes)
+}
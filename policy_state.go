@@ -0,0 +1,144 @@
+package main
+
+//
+//  policy_state.go  --  VIP State ('state') Policy Handler
+//
+//  The State Policy lets OPA decide if a virtual-server, or any of its virtual ports, should
+//  be enabled or disabled. OPA is asked about each VIP by name, and is expected to return
+//  something like this:
+//
+//  {
+//    "state": "dark",
+//    "dark-at": "2022-06-01T00:00:00Z",
+//    "ports": [ {"port": 8080, "protocol": "http", "state": "disable"} ]
+//  }
+//
+//  'state' is one of "enable", "disable" or "dark". A "dark" VIP is an App that is being
+//  decommissioned -- it stays up until the 'dark-at' time (RFC3339) and is then disabled.
+//  A "dark" state with no 'dark-at' time goes dark right away.  The 'ports' list is optional.
+//
+//  As a safety net, no more than config.STATE_MAX_DISABLE (a fraction, rounded up) of the VIPs
+//  on the Thunder node, counted over all its partitions, will be disabled in any one pass of
+//  procLoop().
+//
+
+import (
+	"a10/axapi"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+type statePolicy struct{}
+
+func init() {
+	RegisterPolicy("state", statePolicy{})
+}
+
+//---------------------------------------------------------------------------------
+// Query() -- Find the State policy for the Thunder ID & VIP
+func (statePolicy) Query(c *Cycle, v Virtual) (Decision, error) {
+	res, err := queryOPA(c.Config, "vsstate", map[string]string{"vs": v.Name})
+	if err != nil || !res.Get("state").Exists() {
+		return nil, fmt.Errorf("No State Policy found for Virtual Server '%s'", v.Name)
+	}
+	if c.Config.Debug > 7 {
+		fmt.Println(">>>" + res.Raw)
+	}
+	return res, nil
+}
+
+//---------------------------------------------------------------------------------
+// Plan() -- Work out which of the VS & port states need changing
+func (statePolicy) Plan(d axapi.Device, c *Cycle, v Virtual, dec Decision) ([]Change, error) {
+	res := dec.(gjson.Result)
+	vs, ok := c.findVS(v.Name)
+	if !ok {
+		return nil, nil
+	}
+
+	//
+	// Work out what the VS state should be right now
+	state := res.Get("state").Str
+	if state == "dark" {
+		state = "disable"
+		if res.Get("dark-at").Exists() {
+			dark, err := time.Parse(time.RFC3339, res.Get("dark-at").Str)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid 'dark-at' time for Virtual Server %s: %s", v.Name, err)
+			}
+			if time.Now().Before(dark) {
+				log.Infof("Virtual Server %s is scheduled to go dark at %s\n", v.Name, dark.Format(time.RFC3339))
+				state = "enable"
+			}
+		}
+	}
+	if state != "enable" && state != "disable" {
+		return nil, fmt.Errorf("Invalid State Policy '%s' for Virtual Server %s", state, v.Name)
+	}
+
+	//
+	// Set the VS state, if it has changed
+	var changes []Change
+	if vs.Status != state {
+		if state == "disable" && !c.disableOK(vs.Name) {
+			log.Warnf("Not disabling Virtual Server %s: more than %.0f%% of VIPs would be disabled this cycle\n", v.Name, c.Config.STATE_MAX_DISABLE*100)
+		} else {
+			changes = append(changes, Change{
+				Desc: "Setting Virtual Server " + v.Name + " to '" + state + "'",
+				Do:   func(d axapi.Device) error { return d.SetVirtualServerState(v.Name, state) },
+			})
+		}
+	}
+
+	//
+	// ...then the state of any listed virtual ports
+	for _, pp := range res.Get("ports").Array() {
+		pnum := int(pp.Get("port").Int())
+		pst := pp.Get("state").Str
+		if pst != "enable" && pst != "disable" {
+			log.Errorf("Invalid State Policy '%s' for Virtual Server %s port %d\n", pst, v.Name, pnum)
+			continue
+		}
+		for _, vp := range vs.Ports {
+			if vp.PortNumber != pnum || (pp.Get("protocol").Exists() && vp.Protocol != pp.Get("protocol").Str) {
+				continue
+			}
+			if vp.Status == pst {
+				continue
+			}
+			if pst == "disable" && !c.disableOK(vs.Name) {
+				log.Warnf("Not disabling Virtual Server %s port %d: more than %.0f%% of VIPs would be disabled this cycle\n", v.Name, pnum, c.Config.STATE_MAX_DISABLE*100)
+				continue
+			}
+			proto := vp.Protocol
+			changes = append(changes, Change{
+				Desc: "Setting Virtual Server " + v.Name + " port " + strconv.Itoa(pnum) + "+" + proto + " to '" + pst + "'",
+				Do:   func(d axapi.Device) error { return d.SetVirtualPortState(v.Name, pnum, proto, pst) },
+			})
+		}
+	}
+	return changes, nil
+}
+
+//---------------------------------------------------------------------------------
+// Apply() -- Make the planned changes on the Thunder node. The VS & port states don't
+// depend on each other, so keep going past any errors.
+func (statePolicy) Apply(d axapi.Device, c *Cycle, v Virtual, changes []Change) error {
+	var errs []string
+	for _, ch := range changes {
+		log.Info(ch.Desc)
+		if err := ch.Do(d); err != nil {
+			errs = append(errs, ch.Desc+": "+err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
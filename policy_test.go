@@ -0,0 +1,55 @@
+//
+//  policy.go tests
+//
+
+package main
+
+import (
+	"a10/axapi"
+	"testing"
+)
+
+func TestDisableBudget(t *testing.T) {
+	tests := []struct {
+		total int
+		frac  float64
+		max   int
+	}{
+		{0, 0.25, 0},
+		{1, 0.25, 1},
+		{3, 0.25, 1},
+		{4, 0.25, 1},
+		{5, 0.25, 2},
+		{10, 0.5, 5},
+	}
+	for _, tt := range tests {
+		if b := newDisableBudget(tt.total, tt.frac); b.max != tt.max {
+			t.Errorf("%d VIPs at %v: got %d, want %d", tt.total, tt.frac, b.max, tt.max)
+		}
+	}
+}
+
+func TestDisableOK(t *testing.T) {
+	// -- 2 of the 8 VIPs on the Thunder node may go, over both partitions
+	b := newDisableBudget(8, 0.25)
+	shared := newCycle(Configuration{}, "", []axapi.VS{{Name: "a"}, {Name: "b"}}, b)
+	p1 := newCycle(Configuration{}, "p1", []axapi.VS{{Name: "a"}, {Name: "c"}}, b)
+
+	steps := []struct {
+		c    *Cycle
+		vs   string
+		want bool
+	}{
+		{shared, "a", true},
+		{shared, "a", true}, // already counted
+		{p1, "a", true},     // same name, other partition
+		{p1, "c", false},
+		{shared, "b", false},
+		{p1, "a", true},
+	}
+	for i, s := range steps {
+		if got := s.c.disableOK(s.vs); got != s.want {
+			t.Errorf("step %d: disableOK(%s/%s) got %v", i, s.c.part, s.vs, got)
+		}
+	}
+}
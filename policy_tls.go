@@ -0,0 +1,161 @@
+package main
+
+//
+//  policy_tls.go  --  TLS ('tls') Policy Handler
+//
+//  The TLS Policy lets Security set the allowed TLS protocol versions, cipher suites and the
+//  certificate to use for each VIP centrally in OPA. OPA is asked about each VIP by name, and
+//  is expected to return something like this:
+//
+//  {
+//    "min-version": "TLS1.2",
+//    "max-version": "TLS1.3",
+//    "ciphers": [ "TLS1_ECDHE_RSA_AES_128_GCM_SHA256", "TLS1_ECDHE_RSA_AES_256_GCM_SHA384" ],
+//    "cert": "ws-cert",
+//    "key": "ws-key"
+//  }
+//
+//  'max-version' defaults to TLS1.3, and 'key' defaults to the same name as 'cert'. The cert &
+//  key must already be imported on the Thunder node. A client-ssl template is created for the
+//  VIP and attached to all of its HTTPS ports. Once done, the 'slb' section will look
+//  something like this:
+//
+//  slb template client-ssl opa-policy-tls-ws-vip
+//    certificate ws-cert key ws-key
+//    cipher TLS1_ECDHE_RSA_AES_128_GCM_SHA256
+//    cipher TLS1_ECDHE_RSA_AES_256_GCM_SHA384
+//    version 34 33
+//  slb virtual-server ws-vip 44.147.45.44
+//  	port 443 https
+//  		source-nat auto
+//  		service-group ws-sg
+//  		template client-ssl opa-policy-tls-ws-vip
+//
+
+import (
+	"a10/axapi"
+	"fmt"
+	"strconv"
+
+	"github.com/tidwall/gjson"
+)
+
+type tlsPolicy struct{}
+
+// tlsVersions maps the TLS Policy protocol names to the ACOS client-ssl version numbers
+var tlsVersions = map[string]int{
+	"SSL3.0": 30,
+	"TLS1.0": 31,
+	"TLS1.1": 32,
+	"TLS1.2": 33,
+	"TLS1.3": 34,
+}
+
+func init() {
+	RegisterPolicy("tls", tlsPolicy{})
+}
+
+//---------------------------------------------------------------------------------
+// Query() -- Find the TLS policy for the Thunder ID & VIP
+func (tlsPolicy) Query(c *Cycle, v Virtual) (Decision, error) {
+	res, err := queryOPA(c.Config, "tls", map[string]string{"vs": v.Name})
+	if err != nil || !res.Get("min-version").Exists() {
+		return nil, fmt.Errorf("No TLS Policy found for Virtual Server '%s'", v.Name)
+	}
+	if c.Config.Debug > 7 {
+		fmt.Println(">>>" + res.Raw)
+	}
+	return res, nil
+}
+
+//---------------------------------------------------------------------------------
+// Plan() -- Configure the client-ssl Template for the VIP, and attach it to the HTTPS ports
+func (tlsPolicy) Plan(d axapi.Device, c *Cycle, v Virtual, dec Decision) ([]Change, error) {
+	res := dec.(gjson.Result)
+	maxv := "TLS1.3"
+	if res.Get("max-version").Exists() {
+		maxv = res.Get("max-version").Str
+	}
+	dgver, ok1 := tlsVersions[res.Get("min-version").Str]
+	ver, ok2 := tlsVersions[maxv]
+	if !ok1 || !ok2 || dgver > ver {
+		return nil, fmt.Errorf("Invalid TLS versions in TLS Policy for Virtual Server %s", v.Name)
+	}
+	want := axapi.ClientSSLTemplate{Name: "opa-policy-tls-" + v.Name, Version: ver, DGVersion: dgver}
+	for _, cc := range res.Get("ciphers").Array() {
+		want.Ciphers = append(want.Ciphers, axapi.ClientSSLCipher{Cipher: cc.Str})
+	}
+	if cert := res.Get("cert").Str; cert != "" {
+		key := cert
+		if res.Get("key").Exists() {
+			key = res.Get("key").Str
+		}
+		want.Certs = []axapi.ClientSSLCert{{Cert: cert, Key: key}}
+	}
+	tpl := want.Name
+	if c.Config.Debug > 7 {
+		fmt.Printf(">>>%+v\n", want)
+	}
+
+	// -- First, check to see if Template already exists
+	cur, err := d.GetClientSSLTemplate(tpl)
+	if err != nil && !axapi.IsNotFound(err) {
+		return nil, fmt.Errorf("Error on GetClientSSLTemplate(): %s", err)
+	}
+	// if not, create, else, update
+	var changes []Change
+	if axapi.IsNotFound(err) {
+		changes = append(changes, Change{
+			Desc: "Creating TLS Policy Template...",
+			Do:   func(d axapi.Device) error { return d.CreateClientSSLTemplate(want) },
+		})
+	} else if !tlsSame(want, cur) {
+		changes = append(changes, Change{
+			Desc: "Updating TLS Policy Template",
+			Do:   func(d axapi.Device) error { return d.UpdateClientSSLTemplate(want) },
+		})
+	}
+
+	//
+	// Add Template to the HTTPS ports on the SLB
+	vs, _ := c.findVS(v.Name)
+	for _, vp := range vs.Ports {
+		if vp.Protocol != "https" || vp.ClientSSL == tpl {
+			continue
+		}
+		pnum := vp.PortNumber
+		changes = append(changes, Change{
+			Desc: "Attaching TLS Policy Template to Virtual Server " + v.Name + " port " + strconv.Itoa(pnum),
+			Do: func(d axapi.Device) error {
+				return d.UpdateVirtualPortTyped(v.Name, axapi.Port{PortNumber: pnum, Protocol: "https", ClientSSL: tpl})
+			},
+		})
+	}
+	return changes, nil
+}
+
+//---------------------------------------------------------------------------------
+// tlsSame() -- Does the current client-ssl Template already match the TLS Policy?
+func tlsSame(want axapi.ClientSSLTemplate, cur axapi.ClientSSLTemplate) bool {
+	if want.Version != cur.Version || want.DGVersion != cur.DGVersion ||
+		len(want.Ciphers) != len(cur.Ciphers) || len(want.Certs) != len(cur.Certs) {
+		return false
+	}
+	for i := range want.Ciphers {
+		if want.Ciphers[i] != cur.Ciphers[i] {
+			return false
+		}
+	}
+	for i := range want.Certs {
+		if want.Certs[i] != cur.Certs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//---------------------------------------------------------------------------------
+// Apply() -- Make the planned changes on the Thunder node
+func (tlsPolicy) Apply(d axapi.Device, c *Cycle, v Virtual, changes []Change) error {
+	return applyChanges(d, changes)
+}